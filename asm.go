@@ -0,0 +1,43 @@
+package main
+
+import (
+	"chip-8/asm"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// asmCommand implements "chip-8 asm source.asm".
+func asmCommand(args []string) {
+	flags := flag.NewFlagSet("asm", flag.ExitOnError)
+	output := flags.String("o", "", "ROM file to write (default: the source file with a .ch8 extension)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: chip-8 asm [-o rom.ch8] source.asm")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	source_path := flags.Arg(0)
+	rom, err := asm.AssembleFile(source_path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	rom_path := *output
+	if rom_path == "" {
+		rom_path = strings.TrimSuffix(source_path, filepath.Ext(source_path)) + ".ch8"
+	}
+
+	if err := os.WriteFile(rom_path, rom, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
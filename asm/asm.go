@@ -0,0 +1,367 @@
+// Package asm assembles Cowgod style CHIP-8 source into ROMs.
+//
+// Each line holds an optional label, an instruction or directive and an
+// optional comment starting with ";":
+//
+//	loop:   LD V0, K        ; wait for a key
+//	        JP loop
+//	SPEED   EQU 2
+//	sprite: DB 0xF0, 0x90, 0xF0
+//	        INCLUDE "font.asm"
+//
+// Numbers can be decimal, hex (0x1F, #1F or $1F) or binary (0b101 or
+// %101), and anywhere a number goes a label, a constant or a sum such as
+// "sprite+2" can be used instead. Besides the instructions there are DB
+// for bytes and strings, DW for big endian words, EQU for constants and
+// INCLUDE for other source files. The XO-CHIP F000 NNNN is written
+// "LD I, LONG addr". This is the syntax the disassembler writes, so a
+// listing assembles back into the ROM it came from.
+package asm
+
+import (
+	"chip-8/cpu"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Error is a problem with the source at a line and column.
+type Error struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// ErrorList is every Error found in one run of the assembler.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	messages := make([]string, len(l))
+	for i, err := range l {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+type position struct {
+	file   string
+	line   int
+	column int
+}
+
+type operand struct {
+	text string
+	position
+}
+
+type statement struct {
+	position
+	mnemonic string
+	operands []operand
+	address  uint16
+}
+
+type constant struct {
+	expression operand
+	value      int
+	resolved   bool
+	resolving  bool
+}
+
+type assembler struct {
+	address    int
+	statements []statement
+	labels     map[string]int
+	constants  map[string]*constant
+	including  map[string]bool
+	errors     ErrorList
+}
+
+var (
+	labelPattern    = regexp.MustCompile(`^\s*([A-Za-z_.][A-Za-z0-9_.]*):`)
+	namePattern     = regexp.MustCompile(`^[A-Za-z_.][A-Za-z0-9_.]*$`)
+	registerPattern = regexp.MustCompile(`^[Vv]([0-9A-Fa-f])$`)
+)
+
+// AssembleFile assembles the source file at path. INCLUDE paths are
+// relative to the file that includes them.
+func AssembleFile(path string) ([]byte, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Assemble(path, source)
+}
+
+// Assemble assembles source into a ROM that loads at START_ADDRESS. name
+// is used in error messages and to find included files.
+func Assemble(name string, source []byte) ([]byte, error) {
+	a := assembler{
+		address:   int(cpu.START_ADDRESS),
+		labels:    map[string]int{},
+		constants: map[string]*constant{},
+		including: map[string]bool{},
+	}
+
+	a.parse(name, string(source))
+
+	rom := []byte{}
+	if len(a.errors) == 0 {
+		for _, s := range a.statements {
+			rom = append(rom, a.encode(s)...)
+		}
+	}
+
+	if len(a.errors) > 0 {
+		return nil, a.errors
+	}
+
+	return rom, nil
+}
+
+func (a *assembler) errorf(at position, format string, args ...any) {
+	a.errors = append(a.errors, &Error{
+		File:    at.file,
+		Line:    at.line,
+		Column:  at.column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// parse splits the source into statements and gives every label an
+// address. Nothing is encoded yet, so labels can be used before they are
+// defined.
+func (a *assembler) parse(name string, source string) {
+	if abs, err := filepath.Abs(name); err == nil {
+		if a.including[abs] {
+			a.errorf(position{file: name, line: 1, column: 1}, "%s includes itself", name)
+			return
+		}
+
+		a.including[abs] = true
+		defer delete(a.including, abs)
+	}
+
+	for number, line := range strings.Split(source, "\n") {
+		line = stripComment(strings.TrimRight(line, "\r"))
+		at := position{file: name, line: number + 1, column: 1}
+
+		if match := labelPattern.FindStringSubmatchIndex(line); match != nil {
+			label := line[match[2]:match[3]]
+			a.define(label, position{file: name, line: number + 1, column: match[2] + 1})
+
+			line = strings.Repeat(" ", match[1]) + line[match[1]:]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		at.column = strings.Index(line, fields[0]) + 1
+		rest := line[at.column-1+len(fields[0]):]
+		operands := splitOperands(rest, position{file: name, line: number + 1, column: at.column + len(fields[0])})
+
+		// NAME EQU value
+		if len(fields) > 1 && strings.EqualFold(fields[1], "EQU") {
+			a.defineConstant(fields[0], at, operands)
+			continue
+		}
+
+		s := statement{
+			position: at,
+			mnemonic: strings.ToUpper(fields[0]),
+			operands: operands,
+			address:  uint16(a.address),
+		}
+
+		if s.mnemonic == "INCLUDE" {
+			a.include(s)
+			continue
+		}
+
+		a.statements = append(a.statements, s)
+		a.address += a.size(s)
+
+		if a.address > int(cpu.RAM_SIZE) {
+			a.errorf(at, "program is larger than memory")
+			return
+		}
+	}
+}
+
+func (a *assembler) define(label string, at position) {
+	if a.isDefined(label) {
+		a.errorf(at, "%s is already defined", label)
+		return
+	}
+
+	a.labels[label] = a.address
+}
+
+func (a *assembler) defineConstant(name string, at position, operands []operand) {
+	if !namePattern.MatchString(name) {
+		a.errorf(at, "invalid constant name %q", name)
+		return
+	}
+
+	if a.isDefined(name) {
+		a.errorf(at, "%s is already defined", name)
+		return
+	}
+
+	// The operands were split after the name, so the first one still
+	// starts with EQU
+	value := operands[0]
+	trimmed := strings.TrimSpace(value.text[3:])
+	if len(operands) != 1 || trimmed == "" {
+		a.errorf(at, "EQU needs exactly one value")
+		return
+	}
+
+	value.column += strings.Index(value.text[3:], trimmed) + 3
+	value.text = trimmed
+
+	a.constants[name] = &constant{expression: value}
+}
+
+func (a *assembler) isDefined(name string) bool {
+	_, label := a.labels[name]
+	_, constant := a.constants[name]
+
+	return label || constant
+}
+
+func (a *assembler) include(s statement) {
+	if len(s.operands) != 1 {
+		a.errorf(s.position, "INCLUDE needs a file name")
+		return
+	}
+
+	path, err := strconv.Unquote(s.operands[0].text)
+	if err != nil {
+		a.errorf(s.operands[0].position, "INCLUDE file name must be quoted")
+		return
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(s.file), path)
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		a.errorf(s.operands[0].position, "%v", err)
+		return
+	}
+
+	a.parse(path, string(source))
+}
+
+// size works out how many bytes a statement takes up, which never depends
+// on the value of a label.
+func (a *assembler) size(s statement) int {
+	switch s.mnemonic {
+	case "DB":
+		size := 0
+		for _, op := range s.operands {
+			if text, err := strconv.Unquote(op.text); err == nil {
+				size += len(text)
+			} else {
+				size++
+			}
+		}
+		return size
+
+	case "DW":
+		return 2 * len(s.operands)
+
+	case "LD":
+		if len(s.operands) == 2 && classify(s.operands[1]) == "LONG" {
+			return 4
+		}
+	}
+
+	return 2
+}
+
+// stripComment drops everything from the first ";" outside a string.
+func stripComment(line string) string {
+	quoted := false
+
+	for i, c := range line {
+		switch {
+		case c == '"' && (i == 0 || line[i-1] != '\\'):
+			quoted = !quoted
+		case c == ';' && !quoted:
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+func splitOperands(text string, at position) []operand {
+	operands := []operand{}
+	if strings.TrimSpace(text) == "" {
+		return operands
+	}
+
+	start := 0
+	quoted := false
+
+	add := func(end int) {
+		part := text[start:end]
+		trimmed := strings.TrimSpace(part)
+		column := at.column + start + strings.Index(part, trimmed)
+
+		operands = append(operands, operand{
+			text:     trimmed,
+			position: position{file: at.file, line: at.line, column: column},
+		})
+	}
+
+	for i, c := range text {
+		switch {
+		case c == '"' && (i == 0 || text[i-1] != '\\'):
+			quoted = !quoted
+		case c == ',' && !quoted:
+			add(i)
+			start = i + 1
+		}
+	}
+	add(len(text))
+
+	return operands
+}
+
+// classify says what kind of operand op is: "V" for a V register, the
+// keyword itself for I, [I], DT, ST, K, F, HF, B and R, "LONG" for a long
+// address and "N" for anything that should be a number.
+func classify(op operand) string {
+	upper := strings.ToUpper(op.text)
+
+	if registerPattern.MatchString(op.text) {
+		return "V"
+	}
+
+	switch upper {
+	case "I", "[I]", "DT", "ST", "K", "F", "HF", "B", "R":
+		return upper
+	}
+
+	if strings.HasPrefix(upper, "LONG ") {
+		return "LONG"
+	}
+
+	return "N"
+}
@@ -0,0 +1,173 @@
+package asm
+
+import (
+	"bytes"
+	"chip-8/cpu"
+	"chip-8/disasm"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssemble(t *testing.T) {
+	source := `
+; draws a sprite and waits
+SPEED   EQU 2
+start:  CLS
+        LD V0, SPEED+1      ; x
+        LD I, sprite
+        DRW V0, V1, 3
+        SHR V2
+        JP V0, start
+loop:   JP loop
+sprite: DB 0xF0, %10010000, $F0
+        DW 0x1234
+        DB "hi"
+`
+
+	rom, err := Assemble("test.asm", []byte(source))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x00, 0xE0,
+		0x60, 0x03,
+		0xA2, 0x0E,
+		0xD0, 0x13,
+		0x82, 0x06,
+		0xB2, 0x00,
+		0x12, 0x0C,
+		0xF0, 0x90, 0xF0,
+		0x12, 0x34,
+		'h', 'i',
+	}, rom)
+}
+
+func TestAssembleInstructions(t *testing.T) {
+	tests := []struct {
+		source string
+		bytes  []byte
+	}{
+		{"SYS 0x123", []byte{0x01, 0x23}},
+		{"SCD 4", []byte{0x00, 0xC4}},
+		{"SCU 4", []byte{0x00, 0xD4}},
+		{"CALL 0x345", []byte{0x23, 0x45}},
+		{"SE V1, 0x20", []byte{0x31, 0x20}},
+		{"SE V1, V2", []byte{0x51, 0x20}},
+		{"SNE VA, -1", []byte{0x4A, 0xFF}},
+		{"SNE VA, VB", []byte{0x9A, 0xB0}},
+		{"SAVE V1, V3", []byte{0x51, 0x32}},
+		{"LOAD V1, V3", []byte{0x51, 0x33}},
+		{"ADD v3, 5", []byte{0x73, 0x05}},
+		{"ADD V3, V4", []byte{0x83, 0x44}},
+		{"ADD I, VE", []byte{0xFE, 0x1E}},
+		{"SHL V2, V3", []byte{0x82, 0x3E}},
+		{"RND V5, 0x0F", []byte{0xC5, 0x0F}},
+		{"SKP V6", []byte{0xE6, 0x9E}},
+		{"SKNP V6", []byte{0xE6, 0xA1}},
+		{"LD I, LONG 0x1234", []byte{0xF0, 0x00, 0x12, 0x34}},
+		{"PLANE 3", []byte{0xF3, 0x01}},
+		{"AUDIO", []byte{0xF0, 0x02}},
+		{"PITCH V7", []byte{0xF7, 0x3A}},
+		{"LD V1, DT", []byte{0xF1, 0x07}},
+		{"LD V1, K", []byte{0xF1, 0x0A}},
+		{"LD DT, V1", []byte{0xF1, 0x15}},
+		{"LD ST, V1", []byte{0xF1, 0x18}},
+		{"LD F, V1", []byte{0xF1, 0x29}},
+		{"LD HF, V1", []byte{0xF1, 0x30}},
+		{"LD B, V1", []byte{0xF1, 0x33}},
+		{"LD [I], V1", []byte{0xF1, 0x55}},
+		{"LD V1, [I]", []byte{0xF1, 0x65}},
+		{"LD R, V1", []byte{0xF1, 0x75}},
+		{"LD V1, R", []byte{0xF1, 0x85}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			rom, err := Assemble("test.asm", []byte(test.source))
+			assert.NoError(t, err)
+			assert.Equal(t, test.bytes, rom)
+		})
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		message string
+	}{
+		{"  FOO V1", "test.asm:1:3: unknown instruction FOO"},
+		{"CLS\nLD V0, 256", "test.asm:2:8: 256 is out of range (256)"},
+		{"JP nowhere", "test.asm:1:4: undefined name nowhere"},
+		{"LD K, V0", "test.asm:1:1: invalid operands for LD"},
+		{"JP V1, 0x200", "test.asm:1:4: jump offset register must be V0"},
+		{"a: CLS\na: CLS", "test.asm:2:1: a is already defined"},
+		{"A EQU B\nB EQU A\nJP A", "test.asm:2:7: A is defined in terms of itself"},
+		{"LD V0, 0x2G", "test.asm:1:8: invalid number 0x2G"},
+		{"LD V0, 1+", "test.asm:1:10: missing value in \"1+\""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			_, err := Assemble("test.asm", []byte(test.source))
+
+			var list ErrorList
+			assert.True(t, errors.As(err, &list))
+			assert.Equal(t, test.message, list[0].Error())
+		})
+	}
+}
+
+func TestAssembleInclude(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "main.asm"), []byte("JP sprite\nINCLUDE \"data.asm\"\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "data.asm"), []byte("sprite: DB 0xFF\n"), 0644))
+
+	rom, err := AssembleFile(filepath.Join(dir, "main.asm"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x12, 0x02, 0xFF}, rom)
+}
+
+func TestAssembleIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "loop.asm"), []byte("INCLUDE \"loop.asm\"\n"), 0644))
+
+	_, err := AssembleFile(filepath.Join(dir, "loop.asm"))
+	assert.ErrorContains(t, err, "includes itself")
+}
+
+func TestAssembleDisassembly(t *testing.T) {
+	for _, name := range []string{"pong.rom", "ibm-logo.ch8", "test-opcode.ch8"} {
+		t.Run(name, func(t *testing.T) {
+			rom, err := os.ReadFile(filepath.Join("..", "roms", name))
+			assert.NoError(t, err)
+
+			var listing bytes.Buffer
+			assert.NoError(t, disasm.Write(&listing, disasm.Disassemble(rom, cpu.START_ADDRESS), disasm.Cowgod))
+
+			assembled, err := Assemble(name, listing.Bytes())
+			assert.NoError(t, err)
+			assert.Equal(t, rom, assembled)
+
+			emulator := cpu.NewEmulator()
+			path := filepath.Join(t.TempDir(), name)
+			assert.NoError(t, os.WriteFile(path, assembled, 0644))
+			assert.NoError(t, emulator.LoadRom(path))
+			assert.Equal(t, rom, emulator.Ram[cpu.START_ADDRESS:int(cpu.START_ADDRESS)+len(rom)])
+		})
+	}
+}
+
+func TestAssembleConformanceRoms(t *testing.T) {
+	for _, name := range []string{"flags", "quirks", "keypad"} {
+		t.Run(name, func(t *testing.T) {
+			rom, err := os.ReadFile(filepath.Join("..", "roms", name+".ch8"))
+			assert.NoError(t, err)
+
+			assembled, err := AssembleFile(filepath.Join("..", "roms", name+".asm"))
+			assert.NoError(t, err)
+			assert.Equal(t, rom, assembled, "roms/%s.ch8 is out of date, run go run . asm roms/%s.asm", name, name)
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package asm
+
+import (
+	"strconv"
+	"strings"
+)
+
+// form is one way of writing an instruction. shape lists the kind of each
+// operand as classify names it, and layout says where each operand goes
+// in the opcode:
+//
+//	x  register in the X nibble     y  register in the Y nibble
+//	0  register that must be V0     -  keyword, nothing to encode
+//	k  byte in the low 8 bits       a  address in the low 12 bits
+//	n  nibble in the low 4 bits     p  nibble in the X nibble
+//	l  16 bit address in the word after the opcode
+type form struct {
+	shape  string
+	layout string
+	opcode uint16
+}
+
+var forms = map[string][]form{
+	"CLS":   {{"", "", 0x00E0}},
+	"RET":   {{"", "", 0x00EE}},
+	"SCD":   {{"N", "n", 0x00C0}},
+	"SCU":   {{"N", "n", 0x00D0}},
+	"SCR":   {{"", "", 0x00FB}},
+	"SCL":   {{"", "", 0x00FC}},
+	"EXIT":  {{"", "", 0x00FD}},
+	"LOW":   {{"", "", 0x00FE}},
+	"HIGH":  {{"", "", 0x00FF}},
+	"SYS":   {{"N", "a", 0x0000}},
+	"JP":    {{"N", "a", 0x1000}, {"VN", "0a", 0xB000}},
+	"CALL":  {{"N", "a", 0x2000}},
+	"SE":    {{"VN", "xk", 0x3000}, {"VV", "xy", 0x5000}},
+	"SNE":   {{"VN", "xk", 0x4000}, {"VV", "xy", 0x9000}},
+	"SAVE":  {{"VV", "xy", 0x5002}},
+	"LOAD":  {{"VV", "xy", 0x5003}},
+	"OR":    {{"VV", "xy", 0x8001}},
+	"AND":   {{"VV", "xy", 0x8002}},
+	"XOR":   {{"VV", "xy", 0x8003}},
+	"SUB":   {{"VV", "xy", 0x8005}},
+	"SHR":   {{"VV", "xy", 0x8006}, {"V", "x", 0x8006}},
+	"SUBN":  {{"VV", "xy", 0x8007}},
+	"SHL":   {{"VV", "xy", 0x800E}, {"V", "x", 0x800E}},
+	"RND":   {{"VN", "xk", 0xC000}},
+	"DRW":   {{"VVN", "xyn", 0xD000}},
+	"SKP":   {{"V", "x", 0xE09E}},
+	"SKNP":  {{"V", "x", 0xE0A1}},
+	"PLANE": {{"N", "p", 0xF001}},
+	"AUDIO": {{"", "", 0xF002}},
+	"PITCH": {{"V", "x", 0xF03A}},
+	"ADD": {
+		{"VN", "xk", 0x7000},
+		{"VV", "xy", 0x8004},
+		{"IV", "-x", 0xF01E},
+	},
+	"LD": {
+		{"VN", "xk", 0x6000},
+		{"VV", "xy", 0x8000},
+		{"IN", "-a", 0xA000},
+		{"ILONG", "-l", 0xF000},
+		{"VDT", "x-", 0xF007},
+		{"VK", "x-", 0xF00A},
+		{"DTV", "-x", 0xF015},
+		{"STV", "-x", 0xF018},
+		{"FV", "-x", 0xF029},
+		{"HFV", "-x", 0xF030},
+		{"BV", "-x", 0xF033},
+		{"[I]V", "-x", 0xF055},
+		{"V[I]", "x-", 0xF065},
+		{"RV", "-x", 0xF075},
+		{"VR", "x-", 0xF085},
+	},
+}
+
+// encode turns a statement into bytes. The statements have all been
+// sized by parse, so every label already has its address.
+func (a *assembler) encode(s statement) []byte {
+	switch s.mnemonic {
+	case "DB":
+		return a.encodeBytes(s)
+	case "DW":
+		return a.encodeWords(s)
+	}
+
+	candidates, ok := forms[s.mnemonic]
+	if !ok {
+		a.errorf(s.position, "unknown instruction %s", s.mnemonic)
+		return make([]byte, a.size(s))
+	}
+
+	shape := ""
+	for _, op := range s.operands {
+		shape += classify(op)
+	}
+
+	for _, f := range candidates {
+		if f.shape == shape {
+			return a.encodeForm(s, f)
+		}
+	}
+
+	a.errorf(s.position, "invalid operands for %s", s.mnemonic)
+	return make([]byte, a.size(s))
+}
+
+func (a *assembler) encodeForm(s statement, f form) []byte {
+	opcode := f.opcode
+	long := -1
+
+	for i, place := range f.layout {
+		op := s.operands[i]
+
+		switch place {
+		case 'x':
+			opcode |= uint16(register(op)) << 8
+		case 'y':
+			opcode |= uint16(register(op)) << 4
+		case '0':
+			if register(op) != 0 {
+				a.errorf(op.position, "jump offset register must be V0")
+			}
+		case 'k':
+			opcode |= uint16(a.number(op, 0xFF, true))
+		case 'a':
+			opcode |= uint16(a.number(op, 0xFFF, false))
+		case 'n':
+			opcode |= uint16(a.number(op, 0xF, false))
+		case 'p':
+			opcode |= uint16(a.number(op, 0xF, false)) << 8
+		case 'l':
+			address := op
+			address.text = strings.TrimSpace(op.text[len("LONG"):])
+			address.column += len(op.text) - len(address.text)
+			long = a.number(address, 0xFFFF, false)
+		}
+	}
+
+	bytes := []byte{byte(opcode >> 8), byte(opcode)}
+	if long >= 0 {
+		bytes = append(bytes, byte(long>>8), byte(long))
+	}
+
+	return bytes
+}
+
+func (a *assembler) encodeBytes(s statement) []byte {
+	bytes := []byte{}
+
+	for _, op := range s.operands {
+		if text, err := strconv.Unquote(op.text); err == nil {
+			bytes = append(bytes, text...)
+			continue
+		}
+
+		bytes = append(bytes, byte(a.number(op, 0xFF, true)))
+	}
+
+	return bytes
+}
+
+func (a *assembler) encodeWords(s statement) []byte {
+	bytes := []byte{}
+
+	for _, op := range s.operands {
+		word := a.number(op, 0xFFFF, true)
+		bytes = append(bytes, byte(word>>8), byte(word))
+	}
+
+	return bytes
+}
+
+// number evaluates op and checks it fits in max. Negative values are
+// allowed down to -(max+1)/2 when signed is set, and come back in two's
+// complement.
+func (a *assembler) number(op operand, max int, signed bool) int {
+	value, ok := a.evaluate(op)
+	if !ok {
+		return 0
+	}
+
+	low := 0
+	if signed {
+		low = -(max + 1) / 2
+	}
+
+	if value < low || value > max {
+		a.errorf(op.position, "%s is out of range (%d)", op.text, value)
+		return 0
+	}
+
+	return value & max
+}
+
+func register(op operand) uint8 {
+	value, _ := strconv.ParseUint(op.text[1:], 16, 4)
+	return uint8(value)
+}
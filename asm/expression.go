@@ -0,0 +1,123 @@
+package asm
+
+import (
+	"strconv"
+	"strings"
+)
+
+// evaluate works out the value of an operand, which is a sum of numbers,
+// labels and constants such as "sprite+2" or "-1". It reports the first
+// problem it finds and returns false.
+func (a *assembler) evaluate(op operand) (int, bool) {
+	total := 0
+	sign := 1
+	start := 0
+
+	for i := 0; i <= len(op.text); i++ {
+		if i < len(op.text) && op.text[i] != '+' && op.text[i] != '-' {
+			continue
+		}
+
+		term := strings.TrimSpace(op.text[start:i])
+
+		// A sign with nothing before it is unary, as in "-1" or "2+-1"
+		if term == "" {
+			if i == len(op.text) {
+				a.errorf(a.at(op, i), "missing value in %q", op.text)
+				return 0, false
+			}
+		} else {
+			at := a.at(op, start+strings.Index(op.text[start:i], term))
+			value, ok := a.term(term, at)
+			if !ok {
+				return 0, false
+			}
+
+			total += sign * value
+			sign = 1
+		}
+
+		if i < len(op.text) && op.text[i] == '-' {
+			sign = -sign
+		}
+
+		start = i + 1
+	}
+
+	return total, true
+}
+
+func (a *assembler) at(op operand, offset int) position {
+	at := op.position
+	at.column += offset
+
+	return at
+}
+
+func (a *assembler) term(term string, at position) (int, bool) {
+	if namePattern.MatchString(term) {
+		if address, ok := a.labels[term]; ok {
+			return address, true
+		}
+
+		if c, ok := a.constants[term]; ok {
+			return a.resolve(term, c, at)
+		}
+
+		a.errorf(at, "undefined name %s", term)
+		return 0, false
+	}
+
+	value, ok := parseNumber(term)
+	if !ok {
+		a.errorf(at, "invalid number %s", term)
+		return 0, false
+	}
+
+	return value, true
+}
+
+// resolve evaluates a constant the first time it is used, so constants
+// can refer to labels and to each other in any order.
+func (a *assembler) resolve(name string, c *constant, at position) (int, bool) {
+	if c.resolved {
+		return c.value, true
+	}
+
+	if c.resolving {
+		a.errorf(at, "%s is defined in terms of itself", name)
+		return 0, false
+	}
+
+	c.resolving = true
+	value, ok := a.evaluate(c.expression)
+	c.resolving = false
+
+	c.value = value
+	c.resolved = ok
+
+	return value, ok
+}
+
+func parseNumber(text string) (int, bool) {
+	base := 10
+	lower := strings.ToLower(text)
+
+	switch {
+	case strings.HasPrefix(lower, "0x"):
+		base, text = 16, text[2:]
+	case strings.HasPrefix(lower, "0b"):
+		base, text = 2, text[2:]
+	case strings.HasPrefix(text, "#"), strings.HasPrefix(text, "$"):
+		base, text = 16, text[1:]
+	case strings.HasPrefix(text, "%"):
+		base, text = 2, text[1:]
+	}
+
+	value, err := strconv.ParseUint(text, base, 16)
+	if err != nil {
+		return 0, false
+	}
+
+	return int(value), true
+}
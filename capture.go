@@ -0,0 +1,56 @@
+package main
+
+import (
+	"chip-8/cpu"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseFrameRange parses "120-300" into an inclusive range of frames,
+// counting from 0. "120-" runs to the end and a single frame captures
+// just that one.
+func parseFrameRange(text string) (int, int, error) {
+	from_text, to_text, found := strings.Cut(text, "-")
+	if !found {
+		to_text = from_text
+	}
+
+	from, err := strconv.Atoi(strings.TrimSpace(from_text))
+	if err != nil || from < 0 {
+		return 0, 0, fmt.Errorf("invalid frame range %q, use FROM-TO such as 120-300", text)
+	}
+
+	if strings.TrimSpace(to_text) == "" {
+		return from, 0, nil
+	}
+
+	to, err := strconv.Atoi(strings.TrimSpace(to_text))
+	if err != nil || to < from {
+		return 0, 0, fmt.Errorf("invalid frame range %q, use FROM-TO such as 120-300", text)
+	}
+
+	// A To of 0 means the end to cpu.Capture
+	if to == 0 {
+		return 0, 0, fmt.Errorf("frame range %q only has the first frame, use -screenshot with -frames 1", text)
+	}
+
+	return from, to, nil
+}
+
+func newCapture(format_name string, frames string, scale int) (*cpu.Capture, error) {
+	format, err := cpu.CaptureFormatByName(format_name)
+	if err != nil {
+		return nil, err
+	}
+
+	capture := cpu.Capture{Format: format, Scale: scale}
+
+	if frames != "" {
+		if capture.From, capture.To, err = parseFrameRange(frames); err != nil {
+			return nil, err
+		}
+	}
+
+	return &capture, nil
+}
@@ -0,0 +1,62 @@
+package cpu
+
+import (
+	"testing"
+)
+
+// A loop that touches most kinds of instruction:
+//
+//	0x200: LD V1, 0x05     0x20E: SE V3, 0x00
+//	0x202: ADD V2, 0x01    0x210: LD V4, V2
+//	0x204: SUB V2, V1      0x212: LD F, V4
+//	0x206: SHR V2          0x214: DRW V0, V0, 5
+//	0x208: AND V3, V2      0x216: LD [I], V3
+//	0x20A: RND V5, 0x0F    0x218: CALL 0x21C
+//	0x20C: ADD I, V5       0x21A: JP 0x200
+//	                       0x21C: RET
+var benchmarkProgram = []uint8{
+	0x61, 0x05, 0x72, 0x01, 0x82, 0x15, 0x82, 0x26,
+	0x83, 0x22, 0xC5, 0x0F, 0xF5, 0x1E, 0x33, 0x00,
+	0x84, 0x20, 0xF4, 0x29, 0xD0, 0x05, 0xF3, 0x55,
+	0x22, 0x1C, 0x12, 0x00, 0x00, 0xEE,
+}
+
+func BenchmarkTick(b *testing.B) {
+	emu := NewEmulator()
+	emu.Random = NewRand(1)
+	copy(emu.Ram[START_ADDRESS:], benchmarkProgram)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := emu.Tick(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRom runs pong for a million instructions per iteration, with
+// the timers ticking every TICKS_PER_FRAME instructions.
+func BenchmarkRom(b *testing.B) {
+	const INSTRUCTIONS = 1000000
+
+	for i := 0; i < b.N; i++ {
+		emu := NewEmulator()
+		emu.Random = NewRand(1)
+		if err := emu.LoadRom("../roms/pong.rom"); err != nil {
+			b.Fatal(err)
+		}
+
+		for n := 0; n < INSTRUCTIONS; n++ {
+			if err := emu.Tick(); err != nil {
+				b.Fatal(err)
+			}
+
+			if n%TICKS_PER_FRAME == 0 {
+				emu.TickTimers()
+			}
+		}
+	}
+
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*INSTRUCTIONS), "ns/instruction")
+}
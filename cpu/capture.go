@@ -0,0 +1,196 @@
+package cpu
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Screenshots and captures are this many image pixels to a CHIP-8 pixel
+// when no scale is given
+const DEFAULT_CAPTURE_SCALE int = 8
+
+// A GIF capture keeps its frames in memory until it's closed, and stops
+// once they take up this many bytes
+const MAX_CAPTURE_MEMORY int = 256 << 20
+
+var ErrCaptureTooLong = errors.New("capture too long for a GIF")
+
+// ScreenImage draws screen, which is width x height pixels, stretched over
+// an image_width x image_height image in colors.
+func ScreenImage(screen []uint8, width uint16, height uint16, image_width int, image_height int, colors []color.RGBA) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, image_width, image_height), screenPalette(colors))
+
+	for y := 0; y < image_height; y++ {
+		row := y * int(height) / image_height * int(width)
+
+		for x := 0; x < image_width; x++ {
+			img.Pix[y*img.Stride+x] = screen[row+x*int(width)/image_width] & 0x3
+		}
+	}
+
+	return img
+}
+
+// WriteScreenshot writes screen to w as a PNG, with each pixel scale image
+// pixels wide. A scale of 0 means DEFAULT_CAPTURE_SCALE.
+func WriteScreenshot(w io.Writer, screen []uint8, width uint16, height uint16, scale int, colors []color.RGBA) error {
+	if scale <= 0 {
+		scale = DEFAULT_CAPTURE_SCALE
+	}
+
+	return png.Encode(w, ScreenImage(screen, width, height, int(width)*scale, int(height)*scale, colors))
+}
+
+type CaptureFormat uint8
+
+const (
+	// CaptureGIF writes an animated GIF when the capture is closed
+	CaptureGIF CaptureFormat = iota
+
+	// CaptureRaw writes each frame straight away as 8-bit RGB, the
+	// rawvideo rgb24 format of ffmpeg
+	CaptureRaw
+)
+
+var captureFormatNames = map[string]CaptureFormat{
+	"gif": CaptureGIF,
+	"raw": CaptureRaw,
+}
+
+func CaptureFormatByName(name string) (CaptureFormat, error) {
+	format, ok := captureFormatNames[name]
+	if !ok {
+		names := make([]string, 0, len(captureFormatNames))
+		for name := range captureFormatNames {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return CaptureGIF, fmt.Errorf("unknown capture format %q (choose from %s)", name, strings.Join(names, ", "))
+	}
+
+	return format, nil
+}
+
+// Capture records the frames a Runner runs. Every frame is the size of the
+// first one captured, so a switch to the SUPER-CHIP hires screen is
+// stretched to fit, as it is in the window.
+type Capture struct {
+	Output io.Writer
+	Format CaptureFormat
+
+	// Scale is the size of a pixel of the first frame, in image pixels. 0
+	// means DEFAULT_CAPTURE_SCALE.
+	Scale  int
+	Colors []color.RGBA
+
+	// From and To limit the capture to the frames numbered From to To,
+	// counting from 0, inclusive. A To of 0 means until the Runner stops.
+	From int
+	To   int
+
+	// MaxMemory is how many bytes of GIF frames to keep before the
+	// capture stops. 0 means MAX_CAPTURE_MEMORY.
+	MaxMemory int
+
+	width  int
+	height int
+	gif    gif.GIF
+	memory int
+	full   bool
+	err    error
+}
+
+// add captures screen if frame is in range.
+func (c *Capture) add(frame int, screen []uint8, width uint16, height uint16) {
+	if c.err != nil || c.full || frame < c.From || (c.To != 0 && frame > c.To) {
+		return
+	}
+
+	if c.width == 0 {
+		scale := c.Scale
+		if scale <= 0 {
+			scale = DEFAULT_CAPTURE_SCALE
+		}
+
+		c.width = int(width) * scale
+		c.height = int(height) * scale
+	}
+
+	img := ScreenImage(screen, width, height, c.width, c.height, c.Colors)
+
+	if c.Format == CaptureRaw {
+		c.writeRaw(img)
+		return
+	}
+
+	// GIF delays are in hundredths of a second, so spread 60 frames over
+	// 100 of them
+	delay := (frame+1)*100/60 - frame*100/60
+
+	// A frame like the last one only makes the last one last longer, so
+	// ROMs waiting on a still screen cost nothing
+	if last := len(c.gif.Image) - 1; last >= 0 && bytes.Equal(c.gif.Image[last].Pix, img.Pix) {
+		c.gif.Delay[last] += delay
+		return
+	}
+
+	max_memory := c.MaxMemory
+	if max_memory <= 0 {
+		max_memory = MAX_CAPTURE_MEMORY
+	}
+
+	if c.memory+len(img.Pix) > max_memory {
+		c.full = true
+		return
+	}
+
+	c.memory += len(img.Pix)
+	c.gif.Image = append(c.gif.Image, img)
+	c.gif.Delay = append(c.gif.Delay, delay)
+}
+
+func (c *Capture) writeRaw(img *image.Paletted) {
+	rgb := make([]byte, 0, len(img.Pix)*3)
+
+	for _, pixel := range img.Pix {
+		r, g, b, _ := img.Palette[pixel].RGBA()
+		rgb = append(rgb, uint8(r>>8), uint8(g>>8), uint8(b>>8))
+	}
+
+	_, c.err = c.Output.Write(rgb)
+}
+
+// Close finishes the capture, which writes the GIF, and returns the first
+// error from writing to Output. A GIF that ran out of memory is written up
+// to where it stopped, and Close returns ErrCaptureTooLong.
+func (c *Capture) Close() error {
+	if c.err != nil || c.Format != CaptureGIF {
+		return c.err
+	}
+
+	if len(c.gif.Image) == 0 {
+		// A GIF needs at least one frame, so an empty capture is a
+		// single background pixel
+		c.gif.Image = []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 1, 1), screenPalette(c.Colors))}
+		c.gif.Delay = []int{0}
+	}
+
+	if err := gif.EncodeAll(c.Output, &c.gif); err != nil {
+		return err
+	}
+
+	if c.full {
+		return fmt.Errorf("%w: stopped after %d MB of frames", ErrCaptureTooLong, c.memory>>20)
+	}
+
+	return nil
+}
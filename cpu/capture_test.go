@@ -0,0 +1,133 @@
+package cpu
+
+import (
+	"bytes"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScreenImage(t *testing.T) {
+	// 2x1 screen with the right pixel on
+	screen := []uint8{0, 1}
+
+	img := ScreenImage(screen, 2, 1, 4, 2, []color.RGBA{{1, 2, 3, 255}})
+
+	assert.Equal(t, 4, img.Bounds().Dx())
+	assert.Equal(t, 2, img.Bounds().Dy())
+	assert.Equal(t, []uint8{0, 0, 1, 1, 0, 0, 1, 1}, img.Pix)
+
+	t.Run("Fills in missing colors with the defaults", func(t *testing.T) {
+		assert.Equal(t, color.RGBA{1, 2, 3, 255}, img.Palette[0])
+		assert.Equal(t, defaultColors[1], img.Palette[1])
+	})
+
+	t.Run("Shrinks hires screens", func(t *testing.T) {
+		img := ScreenImage([]uint8{1, 0, 0, 0, 0, 0, 0, 0}, 4, 2, 2, 1, nil)
+		assert.Equal(t, []uint8{1, 0}, img.Pix)
+	})
+}
+
+func TestWriteScreenshot(t *testing.T) {
+	emu := NewEmulator()
+	emu.Screen[0] = 1
+
+	var out bytes.Buffer
+	assert.NoError(t, WriteScreenshot(&out, emu.Screen, emu.ScreenWidth, emu.ScreenHeight, 2, nil))
+
+	img, err := png.Decode(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, int(SCREEN_WIDTH)*2, img.Bounds().Dx())
+	assert.Equal(t, int(SCREEN_HEIGHT)*2, img.Bounds().Dy())
+	assert.Equal(t, defaultColors[1], img.At(1, 1))
+	assert.Equal(t, defaultColors[0], img.At(2, 0))
+}
+
+func TestCapture(t *testing.T) {
+	// 0x200: DRW V0, V0, 1, 0x202: JP 0x200, which draws 5 times a frame
+	// so that every frame is different
+	program := []uint8{0xD0, 0x01, 0x12, 0x00}
+
+	t.Run("Writes the frames in range to a GIF", func(t *testing.T) {
+		emu := NewEmulator()
+		copy(emu.Ram[START_ADDRESS:], program)
+
+		var out bytes.Buffer
+		capture := Capture{Output: &out, Scale: 1, From: 2, To: 4}
+		headless := Headless{Frames: 10, Output: &bytes.Buffer{}, Capture: &capture}
+		assert.NoError(t, headless.Run(&emu))
+		assert.NoError(t, capture.Close())
+
+		animation, err := gif.DecodeAll(&out)
+		assert.NoError(t, err)
+		assert.Len(t, animation.Image, 3)
+		assert.Equal(t, []int{2, 1, 2}, animation.Delay)
+		assert.Equal(t, int(SCREEN_WIDTH), animation.Config.Width)
+	})
+
+	t.Run("Merges frames that don't change", func(t *testing.T) {
+		emu := NewEmulator()
+		// 0x200: ADD V0, 1, 0x202: JP 0x200
+		copy(emu.Ram[START_ADDRESS:], []uint8{0x70, 0x01, 0x12, 0x00})
+
+		var out bytes.Buffer
+		capture := Capture{Output: &out, Scale: 1}
+		headless := Headless{Frames: 6, Output: &bytes.Buffer{}, Capture: &capture}
+		assert.NoError(t, headless.Run(&emu))
+		assert.NoError(t, capture.Close())
+
+		animation, err := gif.DecodeAll(&out)
+		assert.NoError(t, err)
+		assert.Len(t, animation.Image, 1)
+		assert.Equal(t, []int{10}, animation.Delay)
+	})
+
+	t.Run("Stops a GIF with no end when it runs out of memory", func(t *testing.T) {
+		emu := NewEmulator()
+		copy(emu.Ram[START_ADDRESS:], program)
+
+		var out bytes.Buffer
+		capture := Capture{Output: &out, Scale: 1, MaxMemory: 3 * int(SCREEN_TOTAL)}
+		headless := Headless{Frames: 100, Output: &bytes.Buffer{}, Capture: &capture}
+		assert.NoError(t, headless.Run(&emu))
+		assert.ErrorIs(t, capture.Close(), ErrCaptureTooLong)
+
+		animation, err := gif.DecodeAll(&out)
+		assert.NoError(t, err)
+		assert.Len(t, animation.Image, 3)
+	})
+
+	t.Run("Writes raw RGB frames", func(t *testing.T) {
+		emu := NewEmulator()
+		copy(emu.Ram[START_ADDRESS:], program)
+
+		var out bytes.Buffer
+		capture := Capture{Output: &out, Format: CaptureRaw, Scale: 2}
+		headless := Headless{Frames: 3, Output: &bytes.Buffer{}, Capture: &capture}
+		assert.NoError(t, headless.Run(&emu))
+		assert.NoError(t, capture.Close())
+
+		assert.Equal(t, 3*int(SCREEN_TOTAL)*4*3, out.Len())
+	})
+
+	t.Run("Writes a GIF with no frames", func(t *testing.T) {
+		var out bytes.Buffer
+		capture := Capture{Output: &out}
+		assert.NoError(t, capture.Close())
+
+		_, err := gif.DecodeAll(&out)
+		assert.NoError(t, err)
+	})
+}
+
+func TestCaptureFormatByName(t *testing.T) {
+	format, err := CaptureFormatByName("raw")
+	assert.NoError(t, err)
+	assert.Equal(t, CaptureRaw, format)
+
+	_, err = CaptureFormatByName("mp4")
+	assert.Error(t, err)
+}
@@ -0,0 +1,587 @@
+package cpu
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const debuggerHelp = `Commands (numbers are decimal unless they start with 0x):
+  s, step [n]           run n instructions (default 1)
+  n, next               step over a CALL
+  finish                run until the current subroutine returns
+  c, continue           run until something stops the ROM
+  until <addr>          run until PC reaches addr
+  b, break <addr>       stop when PC reaches addr
+  delete <addr>         remove a breakpoint
+  watch <addr> [r|w|rw] stop when an instruction reads or writes addr
+  unwatch <addr>        remove a watchpoint
+  cond <reg> <op> <n>   stop when a register matches, e.g. cond V3 == 0x10
+                        reg is V0-VF, I, PC, SP, DT or ST, op is one of
+                        == != < <= > >=
+  uncond <index>        remove a condition
+  info                  list breakpoints, watchpoints and conditions
+  set <reg> <n>         change a register, e.g. set I 0x300
+  r, regs               show registers, stack and code around PC
+  screen                print the screen
+  key <k> <down|up>     press or release CHIP-8 key k (0-F)
+  q, quit               leave the debugger
+An empty line repeats the last command.`
+
+type watchpoint struct {
+	read  bool
+	write bool
+}
+
+type condition struct {
+	register string
+	op       string
+	value    uint16
+}
+
+func (c condition) String() string {
+	return fmt.Sprintf("%s %s 0x%X", c.register, c.op, c.value)
+}
+
+// Debugger is a terminal REPL around Emulator.Tick. It reads commands
+// from Input and writes to Output. Timers tick once every TICKS_PER_FRAME
+// instructions, as they would in the Runner.
+type Debugger struct {
+	Emulator *Emulator
+	Input    io.Reader
+	Output   io.Writer
+
+	breakpoints map[uint16]bool
+	watchpoints map[uint16]watchpoint
+	conditions  []condition
+
+	ticks       int
+	watchHit    string
+	interrupted atomic.Bool
+}
+
+func NewDebugger(emulator *Emulator, input io.Reader, output io.Writer) *Debugger {
+	d := &Debugger{
+		Emulator:    emulator,
+		Input:       input,
+		Output:      output,
+		breakpoints: map[uint16]bool{},
+		watchpoints: map[uint16]watchpoint{},
+	}
+
+	emulator.OnMemoryAccess = d.onMemoryAccess
+
+	return d
+}
+
+// Interrupt stops a running continue at the next instruction. It is safe
+// to call from another goroutine, such as a SIGINT handler.
+func (d *Debugger) Interrupt() {
+	d.interrupted.Store(true)
+}
+
+func (d *Debugger) Run() {
+	scanner := bufio.NewScanner(d.Input)
+	last := ""
+
+	d.printState()
+
+	for {
+		fmt.Fprint(d.Output, "(chip-8) ")
+
+		if !scanner.Scan() {
+			fmt.Fprintln(d.Output)
+			return
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			line = last
+		}
+		last = line
+
+		if !d.Execute(line) {
+			return
+		}
+	}
+}
+
+// Execute runs a single command. It returns false once the user quits.
+func (d *Debugger) Execute(line string) bool {
+	e := d.Emulator
+	fields := strings.Fields(line)
+
+	if len(fields) == 0 {
+		return true
+	}
+
+	command, args := fields[0], fields[1:]
+
+	switch command {
+	case "s", "step":
+		count := uint16(1)
+		if len(args) > 0 {
+			var ok bool
+			if count, ok = d.parseNumber(args[0]); !ok {
+				return true
+			}
+		}
+
+		steps := uint16(0)
+		d.run(func() bool {
+			steps++
+			return steps >= count
+		})
+
+	case "n", "next":
+		if e.fetchOpcode()&0xF000 != 0x2000 {
+			d.run(func() bool { return true })
+			break
+		}
+
+		ret := e.ProgramCounter + 2
+		depth := e.StackPointer
+		d.run(func() bool {
+			return e.ProgramCounter == ret && e.StackPointer == depth
+		})
+
+	case "finish":
+		depth := e.StackPointer
+		if depth == 0 {
+			fmt.Fprintln(d.Output, "Not in a subroutine")
+			break
+		}
+
+		d.run(func() bool {
+			return e.StackPointer < depth
+		})
+
+	case "c", "continue":
+		d.run(nil)
+
+	case "until":
+		address, ok := d.parseArg(args, 0)
+		if !ok {
+			break
+		}
+
+		d.run(func() bool {
+			return e.ProgramCounter == address
+		})
+
+	case "b", "break":
+		address, ok := d.parseArg(args, 0)
+		if !ok {
+			break
+		}
+
+		d.breakpoints[address] = true
+		fmt.Fprintf(d.Output, "Breakpoint at 0x%03X\n", address)
+
+	case "delete":
+		address, ok := d.parseArg(args, 0)
+		if !ok {
+			break
+		}
+
+		delete(d.breakpoints, address)
+
+	case "watch":
+		address, ok := d.parseArg(args, 0)
+		if !ok {
+			break
+		}
+
+		mode := "rw"
+		if len(args) > 1 {
+			mode = args[1]
+		}
+
+		if mode != "r" && mode != "w" && mode != "rw" {
+			fmt.Fprintf(d.Output, "Unknown watch mode %q\n", mode)
+			break
+		}
+
+		d.watchpoints[address] = watchpoint{
+			read:  strings.Contains(mode, "r"),
+			write: strings.Contains(mode, "w"),
+		}
+		fmt.Fprintf(d.Output, "Watchpoint (%s) at 0x%03X\n", mode, address)
+
+	case "unwatch":
+		address, ok := d.parseArg(args, 0)
+		if !ok {
+			break
+		}
+
+		delete(d.watchpoints, address)
+
+	case "cond":
+		if len(args) != 3 {
+			fmt.Fprintln(d.Output, "Usage: cond <reg> <op> <n>")
+			break
+		}
+
+		value, ok := d.parseNumber(args[2])
+		if !ok {
+			break
+		}
+
+		c := condition{register: strings.ToUpper(args[0]), op: args[1], value: value}
+		if _, ok := d.register(c.register); !ok {
+			fmt.Fprintf(d.Output, "Unknown register %q\n", args[0])
+			break
+		}
+
+		if _, ok := compare(0, c.op, 0); !ok {
+			fmt.Fprintf(d.Output, "Unknown operator %q\n", c.op)
+			break
+		}
+
+		d.conditions = append(d.conditions, c)
+		fmt.Fprintf(d.Output, "Condition %d: %s\n", len(d.conditions)-1, c)
+
+	case "uncond":
+		index, ok := d.parseArg(args, 0)
+		if !ok {
+			break
+		}
+
+		if int(index) >= len(d.conditions) {
+			fmt.Fprintf(d.Output, "No condition %d\n", index)
+			break
+		}
+
+		d.conditions = append(d.conditions[:index], d.conditions[index+1:]...)
+
+	case "set":
+		if len(args) != 2 {
+			fmt.Fprintln(d.Output, "Usage: set <reg> <n>")
+			break
+		}
+
+		value, ok := d.parseNumber(args[1])
+		if !ok {
+			break
+		}
+
+		if !d.setRegister(strings.ToUpper(args[0]), value) {
+			fmt.Fprintf(d.Output, "Can't set %s to 0x%X\n", args[0], value)
+		}
+
+	case "info":
+		d.printInfo()
+
+	case "r", "regs":
+		d.printState()
+
+	case "screen":
+		headless := Headless{Output: d.Output}
+		headless.DrawScreen(e.Screen, e.ScreenWidth, e.ScreenHeight)
+
+	case "key":
+		key, ok := d.parseArg(args, 0)
+		if !ok {
+			break
+		}
+
+		if key > 0xF || len(args) < 2 || (args[1] != "down" && args[1] != "up") {
+			fmt.Fprintln(d.Output, "Usage: key <0-F> <down|up>")
+			break
+		}
+
+		if args[1] == "down" {
+			e.Key(uint8(key), 1)
+		} else {
+			e.Key(uint8(key), 0)
+		}
+
+	case "h", "help":
+		fmt.Fprintln(d.Output, debuggerHelp)
+
+	case "q", "quit":
+		return false
+
+	default:
+		fmt.Fprintf(d.Output, "Unknown command %q, try help\n", command)
+	}
+
+	return true
+}
+
+// run executes instructions until done returns true or something stops the
+// ROM, then shows where it stopped. A nil done runs until a breakpoint,
+// watchpoint or condition fires, the ROM halts, or Interrupt is called.
+func (d *Debugger) run(done func() bool) {
+	e := d.Emulator
+	d.interrupted.Store(false)
+
+	for {
+		if e.Exited {
+			fmt.Fprintln(d.Output, "The ROM has exited")
+			break
+		}
+
+		pc := e.ProgramCounter
+		if err := d.tick(); err != nil {
+			fmt.Fprintln(d.Output, err)
+			break
+		}
+
+		if d.watchHit != "" {
+			fmt.Fprintln(d.Output, d.watchHit)
+			break
+		}
+
+		if done != nil && done() {
+			break
+		}
+
+		if e.ProgramCounter == pc && e.HaltReason() == HaltSelfJump {
+			fmt.Fprintf(d.Output, "Halted, 0x%03X jumps to itself\n", pc)
+			break
+		}
+
+		// Fx0A stays on the same instruction until a key is pressed
+		if e.ProgramCounter == pc {
+			fmt.Fprintf(d.Output, "Waiting for a key at 0x%03X\n", pc)
+			break
+		}
+
+		if d.breakpoints[e.ProgramCounter] {
+			fmt.Fprintf(d.Output, "Breakpoint at 0x%03X\n", e.ProgramCounter)
+			break
+		}
+
+		if c, ok := d.matchingCondition(); ok {
+			fmt.Fprintf(d.Output, "Condition %s\n", c)
+			break
+		}
+
+		if d.interrupted.Load() {
+			fmt.Fprintln(d.Output, "Interrupted")
+			break
+		}
+	}
+
+	d.printState()
+}
+
+// tick runs one instruction. A ROM stopped by an error is resumed, so
+// stepping tries the instruction again, after set has fixed what it needed.
+func (d *Debugger) tick() error {
+	d.watchHit = ""
+	d.Emulator.Resume()
+	if err := d.Emulator.Tick(); err != nil {
+		return err
+	}
+
+	d.ticks++
+
+	if d.ticks%TICKS_PER_FRAME == 0 {
+		d.Emulator.TickTimers()
+	}
+
+	return nil
+}
+
+func (d *Debugger) onMemoryAccess(address uint16, write bool) {
+	w, ok := d.watchpoints[address]
+	if !ok || d.watchHit != "" {
+		return
+	}
+
+	if write && w.write {
+		d.watchHit = fmt.Sprintf("Watchpoint, write to 0x%03X", address)
+	} else if !write && w.read {
+		d.watchHit = fmt.Sprintf("Watchpoint, read from 0x%03X", address)
+	}
+}
+
+func (d *Debugger) matchingCondition() (condition, bool) {
+	for _, c := range d.conditions {
+		value, _ := d.register(c.register)
+		if matched, _ := compare(value, c.op, c.value); matched {
+			return c, true
+		}
+	}
+
+	return condition{}, false
+}
+
+func (d *Debugger) register(name string) (uint16, bool) {
+	e := d.Emulator
+
+	switch name {
+	case "I":
+		return e.IRegister, true
+	case "PC":
+		return e.ProgramCounter, true
+	case "SP":
+		return e.StackPointer, true
+	case "DT":
+		return e.DelayTimer, true
+	case "ST":
+		return e.SoundTimer, true
+	}
+
+	if len(name) == 2 && name[0] == 'V' {
+		if r, err := strconv.ParseUint(name[1:], 16, 8); err == nil {
+			return uint16(e.VRegisters[r]), true
+		}
+	}
+
+	return 0, false
+}
+
+func (d *Debugger) setRegister(name string, value uint16) bool {
+	e := d.Emulator
+
+	switch name {
+	case "I":
+		e.IRegister = value
+	case "PC":
+		e.ProgramCounter = value
+	case "SP":
+		if value > uint16(STACK_SIZE) {
+			return false
+		}
+		e.StackPointer = value
+	case "DT":
+		e.DelayTimer = value
+	case "ST":
+		e.SoundTimer = value
+	default:
+		if len(name) != 2 || name[0] != 'V' || value > 0xFF {
+			return false
+		}
+
+		r, err := strconv.ParseUint(name[1:], 16, 8)
+		if err != nil {
+			return false
+		}
+		e.VRegisters[r] = uint8(value)
+	}
+
+	return true
+}
+
+func compare(a uint16, op string, b uint16) (bool, bool) {
+	switch op {
+	case "==":
+		return a == b, true
+	case "!=":
+		return a != b, true
+	case "<":
+		return a < b, true
+	case "<=":
+		return a <= b, true
+	case ">":
+		return a > b, true
+	case ">=":
+		return a >= b, true
+	}
+
+	return false, false
+}
+
+func (d *Debugger) parseArg(args []string, index int) (uint16, bool) {
+	if len(args) <= index {
+		fmt.Fprintln(d.Output, "Missing argument, try help")
+		return 0, false
+	}
+
+	return d.parseNumber(args[index])
+}
+
+func (d *Debugger) parseNumber(s string) (uint16, bool) {
+	n, err := strconv.ParseUint(s, 0, 16)
+	if err != nil {
+		fmt.Fprintf(d.Output, "Not a number: %q\n", s)
+		return 0, false
+	}
+
+	return uint16(n), true
+}
+
+func (d *Debugger) printState() {
+	e := d.Emulator
+	out := d.Output
+
+	fmt.Fprintf(out, "PC 0x%03X  I 0x%03X  SP %d  DT %d  ST %d\n",
+		e.ProgramCounter, e.IRegister, e.StackPointer, e.DelayTimer, e.SoundTimer)
+
+	for row := 0; row < 2; row++ {
+		registers := make([]string, 8)
+		for i := range registers {
+			registers[i] = fmt.Sprintf("V%X %02X", row*8+i, e.VRegisters[row*8+i])
+		}
+		fmt.Fprintln(out, strings.Join(registers, "  "))
+	}
+
+	fmt.Fprint(out, "Stack")
+	for i := uint16(0); i < e.StackPointer && i < uint16(STACK_SIZE); i++ {
+		fmt.Fprintf(out, " 0x%03X", e.Stack[i])
+	}
+	fmt.Fprintln(out)
+
+	// Start a few instructions back. Instructions are almost always two
+	// bytes, so this lines up with PC unless the ROM mixes in data.
+	address := e.ProgramCounter - 6
+	if e.ProgramCounter < 6 {
+		address = 0
+	}
+
+	for i := 0; i < 8; i++ {
+		text, size := Disassemble(e.Ram[:], address)
+
+		marker := "  "
+		if address == e.ProgramCounter {
+			marker = "=>"
+		}
+
+		fmt.Fprintf(out, "%s 0x%03X  %02X %02X  %s\n", marker, address, e.Ram[address], e.Ram[address+1], text)
+		address += size
+	}
+}
+
+func (d *Debugger) printInfo() {
+	out := d.Output
+
+	breakpoints := make([]int, 0, len(d.breakpoints))
+	for address := range d.breakpoints {
+		breakpoints = append(breakpoints, int(address))
+	}
+	sort.Ints(breakpoints)
+
+	for _, address := range breakpoints {
+		fmt.Fprintf(out, "Breakpoint at 0x%03X\n", address)
+	}
+
+	watchpoints := make([]int, 0, len(d.watchpoints))
+	for address := range d.watchpoints {
+		watchpoints = append(watchpoints, int(address))
+	}
+	sort.Ints(watchpoints)
+
+	for _, address := range watchpoints {
+		w := d.watchpoints[uint16(address)]
+		mode := ""
+		if w.read {
+			mode += "r"
+		}
+		if w.write {
+			mode += "w"
+		}
+
+		fmt.Fprintf(out, "Watchpoint (%s) at 0x%03X\n", mode, address)
+	}
+
+	for i, c := range d.conditions {
+		fmt.Fprintf(out, "Condition %d: %s\n", i, c)
+	}
+}
@@ -0,0 +1,131 @@
+package cpu
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 0x200: CALL 0x208, 0x202: LD V1, 0x02, 0x204: JP 0x204,
+// 0x206: DW 0x0000, 0x208: LD V0, 0x01, 0x20A: LD [I], V0, 0x20C: RET
+var debuggerRom = []uint8{
+	0x22, 0x08, 0x61, 0x02, 0x12, 0x04, 0x00, 0x00,
+	0x60, 0x01, 0xF0, 0x55, 0x00, 0xEE,
+}
+
+func newDebuggerEmulator() (*Emulator, *Debugger, *bytes.Buffer) {
+	emu := NewEmulator()
+	copy(emu.Ram[START_ADDRESS:], debuggerRom)
+	emu.IRegister = 0x300
+
+	var out bytes.Buffer
+	return &emu, NewDebugger(&emu, strings.NewReader(""), &out), &out
+}
+
+func TestDebuggerStep(t *testing.T) {
+	t.Run("step enters a subroutine", func(t *testing.T) {
+		emu, d, _ := newDebuggerEmulator()
+		d.Execute("step")
+
+		assert.Equal(t, uint16(0x208), emu.ProgramCounter)
+	})
+
+	t.Run("next steps over a subroutine", func(t *testing.T) {
+		emu, d, _ := newDebuggerEmulator()
+		d.Execute("next")
+
+		assert.Equal(t, uint16(0x202), emu.ProgramCounter)
+		assert.Equal(t, uint8(1), emu.VRegisters[0])
+	})
+
+	t.Run("finish steps out of a subroutine", func(t *testing.T) {
+		emu, d, _ := newDebuggerEmulator()
+		d.Execute("step")
+		d.Execute("finish")
+
+		assert.Equal(t, uint16(0x202), emu.ProgramCounter)
+		assert.Equal(t, uint16(0), emu.StackPointer)
+	})
+
+	t.Run("until runs to an address", func(t *testing.T) {
+		emu, d, _ := newDebuggerEmulator()
+		d.Execute("until 0x20C")
+
+		assert.Equal(t, uint16(0x20C), emu.ProgramCounter)
+	})
+}
+
+func TestDebuggerContinue(t *testing.T) {
+	t.Run("Stops at a breakpoint", func(t *testing.T) {
+		emu, d, out := newDebuggerEmulator()
+		d.Execute("break 0x20A")
+		d.Execute("continue")
+
+		assert.Equal(t, uint16(0x20A), emu.ProgramCounter)
+		assert.Contains(t, out.String(), "Breakpoint at 0x20A")
+	})
+
+	t.Run("Stops at a watchpoint", func(t *testing.T) {
+		emu, d, out := newDebuggerEmulator()
+		d.Execute("watch 0x300 w")
+		d.Execute("continue")
+
+		assert.Equal(t, uint16(0x20C), emu.ProgramCounter)
+		assert.Contains(t, out.String(), "Watchpoint, write to 0x300")
+	})
+
+	t.Run("Stops on a condition", func(t *testing.T) {
+		emu, d, out := newDebuggerEmulator()
+		d.Execute("cond V1 == 2")
+		d.Execute("continue")
+
+		assert.Equal(t, uint16(0x204), emu.ProgramCounter)
+		assert.Contains(t, out.String(), "Condition V1 == 0x2")
+	})
+
+	t.Run("Stops when the ROM halts", func(t *testing.T) {
+		_, d, out := newDebuggerEmulator()
+		d.Execute("continue")
+
+		assert.Contains(t, out.String(), "Halted, 0x204 jumps to itself")
+	})
+}
+
+func TestDebuggerRun(t *testing.T) {
+	emu := NewEmulator()
+	copy(emu.Ram[START_ADDRESS:], debuggerRom)
+
+	var out bytes.Buffer
+	d := NewDebugger(&emu, strings.NewReader("step\n\nregs\nquit\n"), &out)
+	d.Run()
+
+	assert.Equal(t, uint16(0x20A), emu.ProgramCounter)
+	assert.Contains(t, out.String(), "=> 0x20A  F0 55  LD [I], V0")
+}
+
+func TestDebuggerSet(t *testing.T) {
+	emu, d, out := newDebuggerEmulator()
+	d.Execute("set v3 0x42")
+	d.Execute("set I 0x310")
+	d.Execute("set V3 0x100")
+
+	assert.Equal(t, uint8(0x42), emu.VRegisters[3])
+	assert.Equal(t, uint16(0x310), emu.IRegister)
+	assert.Contains(t, out.String(), "Can't set V3 to 0x100")
+
+	t.Run("Steps past an access out of bounds", func(t *testing.T) {
+		emu, d, _ := newDebuggerEmulator()
+		emu.MemoryPolicy = MemoryHalt
+		emu.IRegister = 0x1000
+		emu.ProgramCounter = 0x20A
+		assert.ErrorIs(t, emu.Tick(), ErrMemoryOutOfBounds)
+
+		d.Execute("set I 0x300")
+		d.Execute("step")
+
+		assert.NoError(t, emu.Err())
+		assert.Equal(t, uint16(0x20C), emu.ProgramCounter)
+	})
+}
@@ -2,289 +2,626 @@ package cpu
 
 import (
 	"fmt"
-	"math/rand"
 )
 
-type Decoder struct {
-	emu *Emulator
+// operands are the parts of an opcode, taken apart once when the opcode
+// table is built rather than every time the opcode runs.
+type operands struct {
+	opcode uint16
+	nnn    uint16
+	x      uint8
+	y      uint8
+	n      uint8
+	nn     uint8
 }
 
-func (d *Decoder) Run(opcode uint16) {
-	e := d.emu
+// opcodeHandler runs one kind of instruction.
+type opcodeHandler func(e *Emulator, op operands) error
 
-	switch opcode & 0xF000 {
-	case 0x0000:
-		switch opcode {
-		case 0x00E0:
-			for i := range e.Screen {
-				e.Screen[i] = 0
-			}
+// decodedOpcode is everything needed to run an opcode: its handler, the
+// first machine that has the instruction, and its operands.
+type decodedOpcode struct {
+	run     opcodeHandler
+	machine Machine
+	operands
+}
+
+// opcodeTable holds every possible opcode already decoded by
+// DecodeInstruction, so running one is a lookup and a call.
+var opcodeTable = buildOpcodeTable()
 
-		case 0x00EE:
-			e.ProgramCounter = e.Pop()
+func buildOpcodeTable() *[0x10000]decodedOpcode {
+	table := new([0x10000]decodedOpcode)
+	word := make([]byte, 2)
 
-		default:
-			fmt.Printf("Invalid opcode %X\n", opcode)
+	for i := range table {
+		word[0], word[1] = uint8(i>>8), uint8(i)
+		instruction := DecodeInstruction(word, 0)
+
+		run, ok := opHandlers[instruction.Op]
+		if !ok {
+			run = runInvalid
 		}
 
-	case 0x1000:
-		nnn := opcode & 0x0FFF
-		e.ProgramCounter = nnn
+		table[i] = decodedOpcode{
+			run:     run,
+			machine: instruction.Op.Machine(),
+			operands: operands{
+				opcode: instruction.Opcode,
+				nnn:    instruction.NNN,
+				x:      instruction.X,
+				y:      instruction.Y,
+				n:      instruction.N,
+				nn:     instruction.NN,
+			},
+		}
+	}
 
-	case 0x2000:
-		oldValue := e.ProgramCounter
-		e.Push(oldValue)
+	return table
+}
 
-		nnn := opcode & 0x0FFF
-		e.ProgramCounter = nnn
+// Decode executes one opcode. It returns an error wrapping ErrInvalidOpcode
+// for opcodes the machine doesn't have, or the stack error from a call or
+// return.
+func (e *Emulator) Decode(opcode uint16) error {
+	decoded := &opcodeTable[opcode]
 
-	case 0x3000:
-		x := (opcode & 0x0F00) >> 8
-		nn := uint8(opcode & 0x00FF)
+	if e.Machine < decoded.machine {
+		return invalidOpcode(opcode)
+	}
 
-		if e.VRegisters[x] == nn {
-			e.ProgramCounter += 2
-		} else {
-		}
+	return decoded.run(e, decoded.operands)
+}
 
-	case 0x4000:
-		x := (opcode & 0x0F00) >> 8
-		nn := uint8(opcode & 0x00FF)
+// opHandlers runs each Op. Ops missing from it, such as the 0nnn machine
+// code call, are invalid.
+var opHandlers = map[Op]opcodeHandler{
+	OpClear:            runClear,
+	OpReturn:           runReturn,
+	OpScrollDown:       runScrollDown,
+	OpScrollUp:         runScrollUp,
+	OpScrollRight:      runScrollRight,
+	OpScrollLeft:       runScrollLeft,
+	OpExit:             runExit,
+	OpLowRes:           runLowRes,
+	OpHighRes:          runHighRes,
+	OpJump:             runJump,
+	OpCall:             runCall,
+	OpSkipEqualByte:    runSkipEqualByte,
+	OpSkipNotEqualByte: runSkipNotEqualByte,
+	OpSkipEqual:        runSkipEqual,
+	OpSaveRange:        runSaveRange,
+	OpLoadRange:        runLoadRange,
+	OpLoadByte:         runLoadByte,
+	OpAddByte:          runAddByte,
+	OpLoad:             runLoad,
+	OpOr:               runOr,
+	OpAnd:              runAnd,
+	OpXor:              runXor,
+	OpAdd:              runAdd,
+	OpSub:              runSub,
+	OpShiftRight:       runShiftRight,
+	OpSubN:             runSubN,
+	OpShiftLeft:        runShiftLeft,
+	OpSkipNotEqual:     runSkipNotEqual,
+	OpLoadI:            runLoadI,
+	OpJumpV0:           runJumpV0,
+	OpRandom:           runRandom,
+	OpDraw:             runDraw,
+	OpSkipKey:          runSkipKey,
+	OpSkipNotKey:       runSkipNotKey,
+	OpLoadLongI:        runLoadLongI,
+	OpPlane:            runPlane,
+	OpAudio:            runAudio,
+	OpGetDelay:         runGetDelay,
+	OpWaitKey:          runWaitKey,
+	OpSetDelay:         runSetDelay,
+	OpSetSound:         runSetSound,
+	OpAddI:             runAddI,
+	OpFont:             runFont,
+	OpBigFont:          runBigFont,
+	OpBCD:              runBCD,
+	OpPitch:            runPitch,
+	OpSaveRegisters:    runSaveRegisters,
+	OpLoadRegisters:    runLoadRegisters,
+	OpSaveFlags:        runSaveFlags,
+	OpLoadFlags:        runLoadFlags,
+}
 
-		if e.VRegisters[x] != nn {
-			e.ProgramCounter += 2
-		} else {
-		}
+func runInvalid(e *Emulator, op operands) error {
+	return invalidOpcode(op.opcode)
+}
 
-	case 0x5000:
-		x := (opcode & 0x0F00) >> 8
-		y := (opcode & 0x00F0) >> 4
+func invalidOpcode(opcode uint16) error {
+	return fmt.Errorf("%w %04X", ErrInvalidOpcode, opcode)
+}
 
-		if e.VRegisters[x] == e.VRegisters[y] {
-			e.ProgramCounter += 2
-		} else {
-		}
+func runClear(e *Emulator, op operands) error {
+	e.ClearScreen()
+	return nil
+}
 
-	case 0x6000:
-		x := (opcode & 0x0F00) >> 8
-		nn := opcode & 0x00FF
-		e.VRegisters[x] = uint8(nn)
-
-	case 0x7000:
-		x := (opcode & 0x0F00) >> 8
-		nn := opcode & 0x00FF
-		e.VRegisters[x] += uint8(nn)
-
-	case 0x8000:
-		switch opcode & 0x000F {
-		case 0:
-			x := (opcode & 0x0F00) >> 8
-			y := (opcode & 0x00F0) >> 4
-			e.VRegisters[x] = e.VRegisters[y]
-
-		case 1:
-			x := (opcode & 0x0F00) >> 8
-			y := (opcode & 0x00F0) >> 4
-			e.VRegisters[x] = e.VRegisters[x] | e.VRegisters[y]
-
-		case 2:
-			x := (opcode & 0x0F00) >> 8
-			y := (opcode & 0x00F0) >> 4
-			e.VRegisters[x] = e.VRegisters[x] & e.VRegisters[y]
-
-		case 3:
-			x := (opcode & 0x0F00) >> 8
-			y := (opcode & 0x00F0) >> 4
-			e.VRegisters[x] = e.VRegisters[x] ^ e.VRegisters[y]
-
-		case 4:
-			x := (opcode & 0x0F00) >> 8
-			y := (opcode & 0x00F0) >> 4
-
-			if e.VRegisters[y] > (255 - e.VRegisters[x]) {
-				e.VRegisters[0xF] = uint8(1)
-			} else {
-				e.VRegisters[0xF] = uint8(0)
-			}
+func runReturn(e *Emulator, op operands) error {
+	address, err := e.Pop()
+	if err != nil {
+		return err
+	}
 
-			e.VRegisters[x] = e.VRegisters[x] + e.VRegisters[y]
+	e.ProgramCounter = address
+	return nil
+}
 
-		case 5:
-			x := (opcode & 0x0F00) >> 8
-			y := (opcode & 0x00F0) >> 4
+func runScrollDown(e *Emulator, op operands) error {
+	e.ScrollDown(uint16(op.n))
+	return nil
+}
 
-			if e.VRegisters[x] > e.VRegisters[y] {
-				e.VRegisters[0xF] = uint8(1)
-			} else {
-				e.VRegisters[0xF] = uint8(0)
-			}
+func runScrollUp(e *Emulator, op operands) error {
+	e.ScrollUp(uint16(op.n))
+	return nil
+}
 
-			e.VRegisters[x] = e.VRegisters[x] - e.VRegisters[y]
+func runScrollRight(e *Emulator, op operands) error {
+	e.ScrollRight(4)
+	return nil
+}
 
-		case 6:
-			x := (opcode & 0x0F00) >> 8
-			v := e.VRegisters[x]
+func runScrollLeft(e *Emulator, op operands) error {
+	e.ScrollLeft(4)
+	return nil
+}
 
-			e.VRegisters[0xF] = v & 1
-			e.VRegisters[x] = v >> 1
+func runExit(e *Emulator, op operands) error {
+	e.Exited = true
+	return nil
+}
 
-		case 7:
-			x := (opcode & 0x0F00) >> 8
-			y := (opcode & 0x00F0) >> 4
+func runLowRes(e *Emulator, op operands) error {
+	e.SetResolution(false)
+	return nil
+}
 
-			if e.VRegisters[y] > e.VRegisters[x] {
-				e.VRegisters[x] = e.VRegisters[y] - e.VRegisters[x]
-				e.VRegisters[0xF] = 1
-			} else {
-				e.VRegisters[x] = 0
-				e.VRegisters[0xF] = 0
-			}
+func runHighRes(e *Emulator, op operands) error {
+	e.SetResolution(true)
+	return nil
+}
+
+func runJump(e *Emulator, op operands) error {
+	e.ProgramCounter = op.nnn
+	return nil
+}
 
-		case 0xE:
-			x := (opcode & 0x0F00) >> 8
-			v := e.VRegisters[x]
+func runCall(e *Emulator, op operands) error {
+	if err := e.Push(e.ProgramCounter); err != nil {
+		return err
+	}
 
-			e.VRegisters[0xF] = v >> 7 & 1
-			e.VRegisters[x] = v << 1
+	e.ProgramCounter = op.nnn
+	return nil
+}
 
-		default:
-			fmt.Printf("Invalid opcode %X\n", opcode)
+func runSkipEqualByte(e *Emulator, op operands) error {
+	if e.VRegisters[op.x] == op.nn {
+		e.skip()
+	}
 
-		}
+	return nil
+}
 
-	case 0x9000:
-		x := (opcode & 0x0F00) >> 8
-		y := (opcode & 0x00F0) >> 4
+func runSkipNotEqualByte(e *Emulator, op operands) error {
+	if e.VRegisters[op.x] != op.nn {
+		e.skip()
+	}
 
-		if e.VRegisters[x] != e.VRegisters[y] {
-			e.ProgramCounter += 2
-		}
+	return nil
+}
+
+func runSkipEqual(e *Emulator, op operands) error {
+	if e.VRegisters[op.x] == e.VRegisters[op.y] {
+		e.skip()
+	}
+
+	return nil
+}
+
+// Save Vx to Vy, in either direction, starting at I
+func runSaveRange(e *Emulator, op operands) error {
+	for i, r := range registerRange(op.x, op.y) {
+		e.writeI(i, e.VRegisters[r])
+	}
+
+	return nil
+}
+
+// Load Vx to Vy, in either direction, starting at I
+func runLoadRange(e *Emulator, op operands) error {
+	for i, r := range registerRange(op.x, op.y) {
+		e.VRegisters[r] = e.readI(i)
+	}
+
+	return nil
+}
+
+func runSkipNotEqual(e *Emulator, op operands) error {
+	if e.VRegisters[op.x] != e.VRegisters[op.y] {
+		e.skip()
+	}
+
+	return nil
+}
+
+func runLoadByte(e *Emulator, op operands) error {
+	e.VRegisters[op.x] = op.nn
+	return nil
+}
+
+func runAddByte(e *Emulator, op operands) error {
+	e.VRegisters[op.x] += op.nn
+	return nil
+}
+
+func runLoad(e *Emulator, op operands) error {
+	e.VRegisters[op.x] = e.VRegisters[op.y]
+	return nil
+}
+
+func runOr(e *Emulator, op operands) error {
+	e.VRegisters[op.x] |= e.VRegisters[op.y]
+	e.resetVF()
+	return nil
+}
+
+func runAnd(e *Emulator, op operands) error {
+	e.VRegisters[op.x] &= e.VRegisters[op.y]
+	e.resetVF()
+	return nil
+}
+
+func runXor(e *Emulator, op operands) error {
+	e.VRegisters[op.x] ^= e.VRegisters[op.y]
+	e.resetVF()
+	return nil
+}
+
+// The arithmetic and shifts set VF after Vx, so the flag wins when x is F
+
+func runAdd(e *Emulator, op operands) error {
+	sum := uint16(e.VRegisters[op.x]) + uint16(e.VRegisters[op.y])
+
+	e.VRegisters[op.x] = uint8(sum)
+	e.VRegisters[0xF] = uint8(sum >> 8)
+	return nil
+}
+
+func runSub(e *Emulator, op operands) error {
+	no_borrow := flagValue(e.VRegisters[op.x] >= e.VRegisters[op.y])
+
+	e.VRegisters[op.x] = e.VRegisters[op.x] - e.VRegisters[op.y]
+	e.VRegisters[0xF] = no_borrow
+	return nil
+}
+
+func runShiftRight(e *Emulator, op operands) error {
+	v := e.shiftSource(op)
+
+	e.VRegisters[op.x] = v >> 1
+	e.VRegisters[0xF] = v & 1
+	return nil
+}
+
+func runSubN(e *Emulator, op operands) error {
+	no_borrow := flagValue(e.VRegisters[op.y] >= e.VRegisters[op.x])
+
+	e.VRegisters[op.x] = e.VRegisters[op.y] - e.VRegisters[op.x]
+	e.VRegisters[0xF] = no_borrow
+	return nil
+}
+
+func runShiftLeft(e *Emulator, op operands) error {
+	v := e.shiftSource(op)
+
+	e.VRegisters[op.x] = v << 1
+	e.VRegisters[0xF] = v >> 7 & 1
+	return nil
+}
+
+func runLoadI(e *Emulator, op operands) error {
+	e.IRegister = op.nnn
+	return nil
+}
+
+// Bnnn jumps to nnn + V0. SUPER-CHIP reads it as Bxnn and adds Vx instead,
+// x being the top digit of nnn.
+func runJumpV0(e *Emulator, op operands) error {
+	v := e.VRegisters[0]
 
-	case 0xA000:
-		nnn := opcode & 0x0FFF
-		e.IRegister = nnn
+	if e.Quirks.JumpVx {
+		v = e.VRegisters[op.x]
+	}
 
-	case 0xB000:
-		nnn := uint8(opcode & 0x0FFF)
-		v := e.VRegisters[0]
-		e.ProgramCounter += uint16(v + nnn)
+	e.ProgramCounter = op.nnn + uint16(v)
+	return nil
+}
 
-	case 0xC000:
-		x := (opcode & 0x0F00) >> 8
-		nn := opcode & 0x00FF
-		random_number := rand.Intn(256)
+func runRandom(e *Emulator, op operands) error {
+	e.VRegisters[op.x] = e.Random.Byte() & op.nn
+	return nil
+}
 
-		e.VRegisters[x] = uint8(random_number) & uint8(nn)
+func runDraw(e *Emulator, op operands) error {
+	n := op.n
 
-	case 0xD000:
-		x := (opcode & 0x0F00) >> 8
-		y := (opcode & 0x00F0) >> 4
-		n := uint8(opcode & 0x000F)
+	start := 0
+	screen_width := e.ScreenWidth
+	screen_height := e.ScreenHeight
 
-		start_addr := e.IRegister
+	// SUPER-CHIP draws a 16x16 sprite, two bytes per row, for Dxy0
+	var sprite_width uint8 = 8
+	if n == 0 && e.Machine >= MachineSuperChip {
+		n = 16
+		if !e.Quirks.LoresTallSprites || e.HiRes() {
+			sprite_width = 16
+		}
+	}
 
-		var i uint8 = 0
-		var j uint8 = 0
+	if e.Quirks.DisplayWait && !e.HiRes() {
+		e.WaitingForFrame = true
+	}
+
+	vx := e.VRegisters[op.x]
+	vy := e.VRegisters[op.y]
+
+	// VF is 1 if any pixel was turned off, by any row or plane
+	e.VRegisters[0xF] = 0
+
+	// XO-CHIP draws to each selected plane in turn, with the sprite for
+	// plane 2 stored straight after the one for plane 1
+	for plane := uint8(1); plane <= 2; plane <<= 1 {
+		if e.Planes&plane == 0 {
+			continue
+		}
 
 		// For each row (n)
-		for i = 0; i < n; i++ {
-			// Get the value from RAM
-			pixels := e.Ram[start_addr+uint16(i)]
+		for i := uint8(0); i < n; i++ {
+			// Get the value from RAM, left aligned in 16 bits
+			var pixels uint16
+			if sprite_width == 16 {
+				pixels = uint16(e.readI(start+int(i)*2))<<8 | uint16(e.readI(start+int(i)*2+1))
+			} else {
+				pixels = uint16(e.readI(start+int(i))) << 8
+			}
 
 			// For each bit (0 or 1) in the RAM value
-			for j = 0; j < 8; j++ {
+			for j := uint8(0); j < sprite_width; j++ {
 				// If the bit equals 1
-				if pixels&(0b10000000>>j) != 0 {
-					x_position := uint16(e.VRegisters[x]+j) % SCREEN_WIDTH
-					y_position := uint16(e.VRegisters[y]+i) % SCREEN_HEIGHT
+				if pixels&(0x8000>>j) == 0 {
+					continue
+				}
+
+				x_position := uint16(vx+j) % screen_width
+				y_position := uint16(vy+i) % screen_height
 
-					screen_index := (y_position * SCREEN_WIDTH) + x_position
+				// Only the starting position wraps, the rest of the sprite
+				// is cut off at the edge
+				if e.Quirks.Clipping {
+					x_position = uint16(vx)%screen_width + uint16(j)
+					y_position = uint16(vy)%screen_height + uint16(i)
 
-					if e.Screen[screen_index] == 1 {
-						e.VRegisters[0xF] = 1
-					} else {
-						e.VRegisters[0xF] = 0
+					if x_position >= screen_width || y_position >= screen_height {
+						continue
 					}
+				}
+
+				screen_index := (y_position * screen_width) + x_position
 
-					e.Screen[screen_index] ^= 1
+				if e.Screen[screen_index]&plane != 0 {
+					e.VRegisters[0xF] = 1
 				}
+
+				e.Screen[screen_index] ^= plane
 			}
 		}
 
-	case 0xE000:
-		switch opcode & 0x00FF {
-		case 0x9E:
-			x := (opcode & 0x0F00) >> 8
+		start += int(n) * int(sprite_width/8)
+	}
 
-			if e.Keys[e.VRegisters[x]] == 1 {
-				e.ProgramCounter += 2
-			}
+	return nil
+}
 
-		case 0xA1:
-			x := (opcode & 0x0F00) >> 8
+func runSkipKey(e *Emulator, op operands) error {
+	if e.Keys[e.VRegisters[op.x]&0xF] == 1 {
+		e.skip()
+	}
 
-			if e.Keys[e.VRegisters[x]] == 0 {
-				e.ProgramCounter += 2
-			}
+	return nil
+}
 
-		default:
-			fmt.Printf("Invalid opcode %X\n", opcode)
+func runSkipNotKey(e *Emulator, op operands) error {
+	if e.Keys[e.VRegisters[op.x]&0xF] == 0 {
+		e.skip()
+	}
 
-		}
+	return nil
+}
 
-	case 0xF000:
-		x := (opcode & 0x0F00) >> 8
+// F000 NNNN loads the 16-bit word after it into I
+func runLoadLongI(e *Emulator, op operands) error {
+	e.IRegister = uint16(e.Ram[e.ProgramCounter])<<8 | uint16(e.Ram[e.ProgramCounter+1])
+	e.ProgramCounter += 2
+	return nil
+}
 
-		switch opcode & 0x00FF {
-		case 0x07:
-			e.VRegisters[x] = uint8(e.DelayTimer)
+func runPlane(e *Emulator, op operands) error {
+	e.Planes = op.x & 0x3
+	return nil
+}
 
-		case 0x0A:
-			pressed := false
+func runAudio(e *Emulator, op operands) error {
+	for i := range e.AudioPattern {
+		e.AudioPattern[i] = e.readI(i)
+	}
 
-			for i, v := range e.Keys {
-				if v == 1 {
-					pressed = true
-					e.VRegisters[x] = uint8(i)
-				}
-			}
+	return nil
+}
+
+func runGetDelay(e *Emulator, op operands) error {
+	e.VRegisters[op.x] = uint8(e.DelayTimer)
+	return nil
+}
+
+// Fx0A waits for a key to be pressed and let go, like the VIP does, so a
+// key held from before doesn't run through several
+func runWaitKey(e *Emulator, op operands) error {
+	if e.KeyHeld && e.Keys[e.HeldKey] == 0 {
+		e.VRegisters[op.x] = e.HeldKey
+		e.KeyHeld = false
+		return nil
+	}
 
-			if !pressed {
-				e.ProgramCounter -= 2
+	if !e.KeyHeld {
+		for i, v := range e.Keys {
+			if v == 1 {
+				e.HeldKey = uint8(i)
+				e.KeyHeld = true
+				break
 			}
+		}
+	}
 
-		case 0x15:
-			e.DelayTimer = uint16(e.VRegisters[x])
+	e.ProgramCounter -= 2
+	return nil
+}
 
-		case 0x18:
-			e.SoundTimer = uint16(e.VRegisters[x])
+func runSetDelay(e *Emulator, op operands) error {
+	e.DelayTimer = uint16(e.VRegisters[op.x])
+	return nil
+}
 
-		case 0x1E:
-			e.IRegister += uint16(e.VRegisters[x])
+func runSetSound(e *Emulator, op operands) error {
+	e.SoundTimer = uint16(e.VRegisters[op.x])
+	return nil
+}
 
-		case 0x29:
-			e.IRegister = uint16(e.VRegisters[x] * 5)
+func runAddI(e *Emulator, op operands) error {
+	e.IRegister += uint16(e.VRegisters[op.x])
+	return nil
+}
 
-		case 0x33:
-			e.Ram[e.IRegister] = e.VRegisters[x] / 100
-			e.Ram[e.IRegister+1] = (e.VRegisters[x] / 10) % 10
-			e.Ram[e.IRegister+2] = e.VRegisters[x] % 10
+func runFont(e *Emulator, op operands) error {
+	e.IRegister = uint16(e.VRegisters[op.x]&0xF) * 5
+	return nil
+}
 
-		case 0x55:
-			for i := 0; i < int(x)+1; i++ {
-				e.Ram[e.IRegister+uint16(i)] = e.VRegisters[i]
-			}
+func runBigFont(e *Emulator, op operands) error {
+	e.IRegister = BIG_FONT_ADDRESS + uint16(e.VRegisters[op.x]&0xF)*10
+	return nil
+}
 
-		case 0x65:
-			for i := 0; i < int(x)+1; i++ {
-				e.VRegisters[i] = e.Ram[e.IRegister+uint16(i)]
-			}
+func runBCD(e *Emulator, op operands) error {
+	v := e.VRegisters[op.x]
 
-		default:
-			fmt.Printf("Invalid opcode %X\n", opcode)
-		}
+	e.writeI(0, v/100)
+	e.writeI(1, (v/10)%10)
+	e.writeI(2, v%10)
+	return nil
+}
+
+func runPitch(e *Emulator, op operands) error {
+	e.Pitch = e.VRegisters[op.x]
+	return nil
+}
+
+func runSaveRegisters(e *Emulator, op operands) error {
+	for i := 0; i < int(op.x)+1; i++ {
+		e.writeI(i, e.VRegisters[i])
+	}
+
+	e.incrementIRegister(op.x)
+	return nil
+}
+
+func runLoadRegisters(e *Emulator, op operands) error {
+	for i := 0; i < int(op.x)+1; i++ {
+		e.VRegisters[i] = e.readI(i)
+	}
+
+	e.incrementIRegister(op.x)
+	return nil
+}
+
+func runSaveFlags(e *Emulator, op operands) error {
+	for i := 0; i < int(op.x)+1 && i < e.rplFlagCount(); i++ {
+		e.RPLFlags[i] = e.VRegisters[i]
+	}
+
+	return nil
+}
+
+func runLoadFlags(e *Emulator, op operands) error {
+	for i := 0; i < int(op.x)+1 && i < e.rplFlagCount(); i++ {
+		e.VRegisters[i] = e.RPLFlags[i]
+	}
+
+	return nil
+}
 
-	default:
-		fmt.Printf("Invalid opcode %X\n", opcode)
+// resetVF clears VF after 8xy1, 8xy2 and 8xy3 when the quirk is on.
+func (e *Emulator) resetVF() {
+	if e.Quirks.VFReset {
+		e.VRegisters[0xF] = 0
 	}
 }
+
+// shiftSource is the value 8xy6 and 8xyE shift, Vy with the quirk on.
+func (e *Emulator) shiftSource(op operands) uint8 {
+	if e.Quirks.ShiftVy {
+		return e.VRegisters[op.y]
+	}
+
+	return e.VRegisters[op.x]
+}
+
+// Fx55 and Fx65 leave I where it was unless a quirk says otherwise.
+func (e *Emulator) incrementIRegister(x uint8) {
+	if e.Quirks.MemoryIncrementByX {
+		e.IRegister += uint16(x)
+	} else if e.Quirks.MemoryIncrement {
+		e.IRegister += uint16(x) + 1
+	}
+}
+
+// flagValue is the value of VF for a flag that is set or not.
+func flagValue(set bool) uint8 {
+	if set {
+		return 1
+	}
+
+	return 0
+}
+
+// skip jumps over the next instruction. On XO-CHIP that can be the four
+// byte F000 NNNN.
+func (e *Emulator) skip() {
+	if e.Machine >= MachineXOChip && e.Ram[e.ProgramCounter] == 0xF0 && e.Ram[e.ProgramCounter+1] == 0x00 {
+		e.ProgramCounter += 2
+	}
+
+	e.ProgramCounter += 2
+}
+
+// registerRange lists the registers from x to y, counting down if y < x.
+func registerRange(x uint8, y uint8) []uint8 {
+	registers := []uint8{}
+
+	if x <= y {
+		for r := x; r <= y; r++ {
+			registers = append(registers, r)
+		}
+	} else {
+		for r := int(x); r >= int(y); r-- {
+			registers = append(registers, uint8(r))
+		}
+	}
+
+	return registers
+}
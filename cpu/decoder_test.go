@@ -1,6 +1,8 @@
 package cpu
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -208,6 +210,17 @@ func TestOpcode8xy5(t *testing.T) {
 		assert.Equal(t, uint8(1), emu.VRegisters[0xF])
 	})
 
+	t.Run("Vx equals Vy", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.VRegisters[2] = 10
+		emu.VRegisters[3] = 10
+		emu.Decode(0x8235)
+
+		// Nothing was borrowed
+		assert.Equal(t, uint8(0), emu.VRegisters[2])
+		assert.Equal(t, uint8(1), emu.VRegisters[0xF])
+	})
+
 	t.Run("Vx is less than Vy", func(t *testing.T) {
 		emu := NewEmulator()
 		emu.VRegisters[2] = 12
@@ -258,9 +271,20 @@ func TestOpcode8xy7(t *testing.T) {
 		emu.VRegisters[3] = 10
 		emu.Decode(0x8237)
 
-		assert.Equal(t, uint8(0), emu.VRegisters[2])
+		// Wraps around rather than stopping at 0
+		assert.Equal(t, uint8(0xFE), emu.VRegisters[2])
 		assert.Equal(t, uint8(0), emu.VRegisters[0xF])
 	})
+
+	t.Run("Vy equals Vx", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.VRegisters[2] = 12
+		emu.VRegisters[3] = 12
+		emu.Decode(0x8237)
+
+		assert.Equal(t, uint8(0), emu.VRegisters[2])
+		assert.Equal(t, uint8(1), emu.VRegisters[0xF])
+	})
 }
 
 func TestOpcode8xyE(t *testing.T) {
@@ -316,22 +340,89 @@ func TestOpcodeAnnn(t *testing.T) {
 }
 
 func TestOpcodeBnnn(t *testing.T) {
-	emu := NewEmulator()
-	emu.ProgramCounter = 2
-	emu.VRegisters[0] = 4
-	emu.Decode(0xB003)
+	t.Run("Jumps to nnn + V0", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.ProgramCounter = 0x300
+		emu.VRegisters[0] = 4
+		emu.Decode(0xB203)
+
+		assert.Equal(t, uint16(0x207), emu.ProgramCounter)
+	})
 
-	assert.Equal(t, uint16(9), emu.ProgramCounter)
+	t.Run("Uses all 12 bits of nnn", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.VRegisters[0] = 0xFF
+		emu.Decode(0xBF00)
+
+		assert.Equal(t, uint16(0xFFF), emu.ProgramCounter)
+	})
 }
 
 func TestOpcodeCxnn(t *testing.T) {
-	// TODO: Find a way to mock rand.Intn
+	t.Run("Same seed gives the same numbers", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Random = NewRand(42)
+		other := NewEmulator()
+		other.Random = NewRand(42)
+
+		for i := 0; i < 8; i++ {
+			emu.Decode(0xC2FF)
+			other.Decode(0xC2FF)
+
+			assert.Equal(t, emu.VRegisters[2], other.VRegisters[2])
+		}
+	})
+
+	t.Run("Masks with nn", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Random = NewRand(42)
+		random := NewRand(42)
+
+		for i := 0; i < 8; i++ {
+			emu.Decode(0xC20F)
+
+			assert.Equal(t, random.Byte()&0x0F, emu.VRegisters[2])
+		}
+	})
 }
 
 func TestOpcodeDxyn(t *testing.T) {
-	emu := NewEmulator()
-	emu.Decode(0xD123)
-	// TODO: hmmm
+	t.Run("Draws the sprite at I", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.IRegister = 0x300
+		emu.Ram[0x300] = 0xC0
+		emu.VRegisters[1] = 2
+		emu.VRegisters[2] = 1
+		emu.Decode(0xD121)
+
+		assert.Equal(t, uint8(1), emu.Screen[SCREEN_WIDTH+2])
+		assert.Equal(t, uint8(1), emu.Screen[SCREEN_WIDTH+3])
+		assert.Equal(t, uint8(0), emu.Screen[SCREEN_WIDTH+4])
+		assert.Equal(t, uint8(0), emu.VRegisters[0xF])
+	})
+
+	t.Run("Keeps VF set after a collision in an earlier row", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.IRegister = 0x300
+		emu.Ram[0x300] = 0x80
+		emu.Ram[0x301] = 0x80
+		emu.Screen[0] = 1
+		emu.Decode(0xD012)
+
+		assert.Equal(t, uint8(0), emu.Screen[0])
+		assert.Equal(t, uint8(1), emu.Screen[SCREEN_WIDTH])
+		assert.Equal(t, uint8(1), emu.VRegisters[0xF])
+	})
+
+	t.Run("Clears VF when nothing collides", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.IRegister = 0x300
+		emu.Ram[0x300] = 0x80
+		emu.VRegisters[0xF] = 1
+		emu.Decode(0xD011)
+
+		assert.Equal(t, uint8(0), emu.VRegisters[0xF])
+	})
 }
 
 func TestOpcodeEx9E(t *testing.T) {
@@ -354,6 +445,16 @@ func TestOpcodeEx9E(t *testing.T) {
 
 		assert.Equal(t, uint16(900), emu.ProgramCounter)
 	})
+
+	t.Run("Only uses the low digit of Vx", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.ProgramCounter = 900
+		emu.VRegisters[1] = 0x23
+		emu.Keys[0x3] = 1
+		emu.Decode(0xE19E)
+
+		assert.Equal(t, uint16(902), emu.ProgramCounter)
+	})
 }
 
 func TestOpcodeEx1A(t *testing.T) {
@@ -396,14 +497,42 @@ func TestOpcodeFx0A(t *testing.T) {
 		assert.Equal(t, uint16(8), emu.ProgramCounter)
 	})
 
-	t.Run("a key is pressed", func(t *testing.T) {
+	t.Run("a key is pressed and released", func(t *testing.T) {
 		emu := NewEmulator()
 		emu.ProgramCounter = 10
 		emu.Keys[0xC] = 1
 		emu.Decode(0xF20A)
 
+		// Still waiting while the key is down
+		assert.Equal(t, uint16(8), emu.ProgramCounter)
+		assert.Equal(t, uint8(0), emu.VRegisters[2])
+
+		emu.ProgramCounter = 10
+		emu.Decode(0xF20A)
+		assert.Equal(t, uint16(8), emu.ProgramCounter)
+
+		emu.ProgramCounter = 10
+		emu.Keys[0xC] = 0
+		emu.Decode(0xF20A)
+
 		assert.Equal(t, uint16(10), emu.ProgramCounter)
 		assert.Equal(t, uint8(0xC), emu.VRegisters[2])
+		assert.False(t, emu.KeyHeld)
+	})
+
+	t.Run("other keys while waiting for a release", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.ProgramCounter = 10
+		emu.Keys[0x3] = 1
+		emu.Decode(0xF20A)
+
+		emu.ProgramCounter = 10
+		emu.Keys[0x3] = 0
+		emu.Keys[0x7] = 1
+		emu.Decode(0xF20A)
+
+		assert.Equal(t, uint16(10), emu.ProgramCounter)
+		assert.Equal(t, uint8(0x3), emu.VRegisters[2])
 	})
 }
 
@@ -441,6 +570,14 @@ func TestOpcodeFx29(t *testing.T) {
 	emu.Decode(0xF229)
 
 	assert.Equal(t, uint16(20), emu.IRegister)
+
+	t.Run("Only uses the low digit of Vx", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.VRegisters[2] = 0x5A
+		emu.Decode(0xF229)
+
+		assert.Equal(t, uint16(50), emu.IRegister)
+	})
 }
 
 func TestOpcodeFx33(t *testing.T) {
@@ -479,3 +616,485 @@ func TestOpcodeFx65(t *testing.T) {
 	assert.Equal(t, uint8(200), emu.VRegisters[1])
 	assert.Equal(t, uint8(255), emu.VRegisters[2])
 }
+
+func TestQuirkVFReset(t *testing.T) {
+	emu := NewEmulator()
+	emu.Quirks.VFReset = true
+	emu.VRegisters[2] = 0xcc
+	emu.VRegisters[3] = 0xaa
+	emu.VRegisters[0xF] = 1
+	emu.Decode(0x8231)
+
+	assert.Equal(t, uint8(0xee), emu.VRegisters[2])
+	assert.Equal(t, uint8(0), emu.VRegisters[0xF])
+}
+
+func TestQuirkShiftVy(t *testing.T) {
+	emu := NewEmulator()
+	emu.Quirks.ShiftVy = true
+	emu.VRegisters[2] = 0x00
+	emu.VRegisters[3] = 0xC1
+	emu.Decode(0x8236)
+
+	assert.Equal(t, uint8(0x60), emu.VRegisters[2])
+	assert.Equal(t, uint8(1), emu.VRegisters[0xF])
+}
+
+func TestQuirkMemoryIncrement(t *testing.T) {
+	t.Run("I += x + 1", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Quirks.MemoryIncrement = true
+		emu.IRegister = 999
+		emu.Decode(0xF255)
+
+		assert.Equal(t, uint16(1002), emu.IRegister)
+	})
+
+	t.Run("I += x", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Quirks.MemoryIncrementByX = true
+		emu.IRegister = 999
+		emu.Decode(0xF265)
+
+		assert.Equal(t, uint16(1001), emu.IRegister)
+	})
+}
+
+func TestQuirkClipping(t *testing.T) {
+	emu := NewEmulator()
+	emu.Quirks.Clipping = true
+	emu.IRegister = 0 // The "0" font sprite
+	emu.VRegisters[0] = 62
+	emu.VRegisters[1] = 30
+	emu.Decode(0xD015)
+
+	assert.Equal(t, uint8(1), emu.Screen[30*SCREEN_WIDTH+62])
+	assert.Equal(t, uint8(1), emu.Screen[31*SCREEN_WIDTH+62])
+	assert.Equal(t, uint8(0), emu.Screen[0])
+	assert.Equal(t, uint8(0), emu.Screen[30*SCREEN_WIDTH])
+}
+
+func TestQuirkJumpVx(t *testing.T) {
+	emu := NewEmulator()
+	emu.Quirks.JumpVx = true
+	emu.ProgramCounter = 0x300
+	emu.VRegisters[0] = 4
+	emu.VRegisters[2] = 1
+	emu.Decode(0xB203)
+
+	assert.Equal(t, uint16(0x204), emu.ProgramCounter)
+}
+
+func TestQuirkLoresTallSprites(t *testing.T) {
+	emu := NewEmulator()
+	emu.Machine = MachineSuperChip
+	emu.Quirks.LoresTallSprites = true
+	emu.IRegister = 0x300
+	for i := 0; i < 32; i++ {
+		emu.Ram[0x300+i] = 0xFF
+	}
+
+	t.Run("Draws 8x16 in low resolution", func(t *testing.T) {
+		emu.Decode(0xD010)
+
+		assert.Equal(t, uint8(1), emu.Screen[15*SCREEN_WIDTH+7])
+		assert.Equal(t, uint8(0), emu.Screen[8])
+		assert.Equal(t, uint8(0), emu.Screen[16*SCREEN_WIDTH])
+	})
+
+	t.Run("Draws 16x16 in high resolution", func(t *testing.T) {
+		emu.SetResolution(true)
+		emu.Decode(0xD010)
+
+		assert.Equal(t, uint8(1), emu.Screen[15*HIRES_SCREEN_WIDTH+15])
+	})
+}
+
+func TestQuirkDisplayWait(t *testing.T) {
+	emu := NewEmulator()
+	emu.Quirks.DisplayWait = true
+	// 0x200: DRW V0, V0, 1, 0x202: JP 0x200
+	copy(emu.Ram[START_ADDRESS:], []uint8{0xD0, 0x01, 0x12, 0x00})
+
+	runner := NewRunner(&fakeFrontend{})
+	runner.FrameDelay = 0
+	runner.Frames = 1
+
+	assert.NoError(t, runner.Run(&emu))
+	assert.Equal(t, uint16(0x202), emu.ProgramCounter)
+
+	t.Run("Not in high resolution", func(t *testing.T) {
+		emu.Machine = MachineSuperChip
+		emu.SetResolution(true)
+		emu.ProgramCounter = START_ADDRESS
+
+		assert.NoError(t, emu.Tick())
+		assert.False(t, emu.WaitingForFrame)
+	})
+}
+
+func TestQuirksSuperChipLegacy(t *testing.T) {
+	assert.NotEqual(t, QuirksSuperChipModern, QuirksSuperChipLegacy)
+
+	emu := NewEmulator()
+	emu.Quirks = QuirksSuperChipLegacy
+	emu.IRegister = 999
+	emu.Decode(0xF255)
+
+	assert.Equal(t, uint16(1001), emu.IRegister)
+}
+
+func TestOpcode00Cn(t *testing.T) {
+	emu := NewEmulator()
+	emu.Machine = MachineSuperChip
+	emu.Screen[1] = 1
+	emu.Decode(0x00C3)
+
+	assert.Equal(t, uint8(0), emu.Screen[1])
+	assert.Equal(t, uint8(1), emu.Screen[3*SCREEN_WIDTH+1])
+}
+
+func TestOpcode00FB(t *testing.T) {
+	emu := NewEmulator()
+	emu.Machine = MachineSuperChip
+	emu.Screen[1] = 1
+	emu.Screen[SCREEN_WIDTH-1] = 1
+	emu.Decode(0x00FB)
+
+	assert.Equal(t, uint8(1), emu.Screen[5])
+	assert.Equal(t, uint8(0), emu.Screen[1])
+	assert.Equal(t, uint8(0), emu.Screen[SCREEN_WIDTH])
+	assert.Equal(t, uint8(0), emu.Screen[SCREEN_WIDTH+3])
+}
+
+func TestOpcode00FC(t *testing.T) {
+	emu := NewEmulator()
+	emu.Machine = MachineSuperChip
+	emu.Screen[5] = 1
+	emu.Screen[SCREEN_WIDTH] = 1
+	emu.Decode(0x00FC)
+
+	assert.Equal(t, uint8(1), emu.Screen[1])
+	assert.Equal(t, uint8(0), emu.Screen[5])
+	assert.Equal(t, uint8(0), emu.Screen[SCREEN_WIDTH-4])
+}
+
+func TestOpcode00FD(t *testing.T) {
+	emu := NewEmulator()
+	emu.Machine = MachineSuperChip
+	emu.Decode(0x00FD)
+
+	assert.True(t, emu.Exited)
+}
+
+func TestOpcode00FE00FF(t *testing.T) {
+	t.Run("SUPER-CHIP switches resolution", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Machine = MachineSuperChip
+		emu.Decode(0x00FF)
+
+		assert.Equal(t, HIRES_SCREEN_WIDTH, emu.ScreenWidth)
+		assert.Equal(t, HIRES_SCREEN_HEIGHT, emu.ScreenHeight)
+		assert.Equal(t, int(HIRES_SCREEN_WIDTH*HIRES_SCREEN_HEIGHT), len(emu.Screen))
+
+		emu.Decode(0x00FE)
+
+		assert.Equal(t, SCREEN_WIDTH, emu.ScreenWidth)
+		assert.Equal(t, int(SCREEN_TOTAL), len(emu.Screen))
+	})
+
+	t.Run("CHIP-8 ignores them", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Decode(0x00FF)
+
+		assert.Equal(t, SCREEN_WIDTH, emu.ScreenWidth)
+	})
+}
+
+func TestOpcodeDxy0(t *testing.T) {
+	emu := NewEmulator()
+	emu.Machine = MachineSuperChip
+	emu.Decode(0x00FF)
+	emu.IRegister = 0x300
+	emu.Ram[0x300] = 0x80
+	emu.Ram[0x301] = 0x01
+	emu.Ram[0x31E] = 0xFF
+	emu.VRegisters[0] = 100
+	emu.VRegisters[1] = 40
+	emu.Decode(0xD010)
+
+	width := uint16(HIRES_SCREEN_WIDTH)
+	assert.Equal(t, uint8(1), emu.Screen[40*width+100])
+	assert.Equal(t, uint8(1), emu.Screen[40*width+115])
+	assert.Equal(t, uint8(0), emu.Screen[40*width+101])
+	assert.Equal(t, uint8(1), emu.Screen[55*width+100])
+	assert.Equal(t, uint8(1), emu.Screen[55*width+107])
+	assert.Equal(t, uint8(0), emu.Screen[55*width+108])
+}
+
+func TestOpcodeFx30(t *testing.T) {
+	emu := NewEmulator()
+	emu.Machine = MachineSuperChip
+	emu.VRegisters[2] = 0x3
+	emu.Decode(0xF230)
+
+	assert.Equal(t, BIG_FONT_ADDRESS+30, emu.IRegister)
+	assert.Equal(t, uint8(0x3C), emu.Ram[emu.IRegister])
+}
+
+func TestOpcodeFx75Fx85(t *testing.T) {
+	emu := NewEmulator()
+	emu.Machine = MachineSuperChip
+	emu.VRegisters[0] = 10
+	emu.VRegisters[1] = 20
+	emu.VRegisters[2] = 30
+	emu.Decode(0xF175)
+
+	assert.Equal(t, uint8(10), emu.RPLFlags[0])
+	assert.Equal(t, uint8(20), emu.RPLFlags[1])
+	assert.Equal(t, uint8(0), emu.RPLFlags[2])
+
+	emu.VRegisters[0] = 0
+	emu.VRegisters[1] = 0
+	emu.Decode(0xF185)
+
+	assert.Equal(t, uint8(10), emu.VRegisters[0])
+	assert.Equal(t, uint8(20), emu.VRegisters[1])
+	assert.Equal(t, uint8(30), emu.VRegisters[2])
+}
+
+func TestOpcode5xy2(t *testing.T) {
+	emu := NewEmulator()
+	emu.Machine = MachineXOChip
+	emu.IRegister = 999
+	emu.VRegisters[1] = 10
+	emu.VRegisters[2] = 20
+	emu.VRegisters[3] = 30
+	emu.Decode(0x5312)
+
+	assert.Equal(t, uint8(30), emu.Ram[999])
+	assert.Equal(t, uint8(20), emu.Ram[1000])
+	assert.Equal(t, uint8(10), emu.Ram[1001])
+	assert.Equal(t, uint16(999), emu.IRegister)
+}
+
+func TestOpcode5xy3(t *testing.T) {
+	emu := NewEmulator()
+	emu.Machine = MachineXOChip
+	emu.IRegister = 999
+	emu.Ram[999] = 10
+	emu.Ram[1000] = 20
+	emu.Decode(0x5123)
+
+	assert.Equal(t, uint8(10), emu.VRegisters[1])
+	assert.Equal(t, uint8(20), emu.VRegisters[2])
+}
+
+func TestOpcodeF000(t *testing.T) {
+	t.Run("Loads a 16-bit address", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Machine = MachineXOChip
+		emu.Ram[0x200] = 0xF0
+		emu.Ram[0x201] = 0x00
+		emu.Ram[0x202] = 0xBE
+		emu.Ram[0x203] = 0xEF
+		emu.Tick()
+
+		assert.Equal(t, uint16(0xBEEF), emu.IRegister)
+		assert.Equal(t, uint16(0x204), emu.ProgramCounter)
+	})
+
+	t.Run("Is skipped as one instruction", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Machine = MachineXOChip
+		emu.ProgramCounter = 0x300
+		emu.Ram[0x300] = 0xF0
+		emu.Ram[0x301] = 0x00
+		emu.Decode(0x3000)
+
+		assert.Equal(t, uint16(0x304), emu.ProgramCounter)
+	})
+}
+
+func TestOpcodeFn01(t *testing.T) {
+	emu := NewEmulator()
+	emu.Machine = MachineXOChip
+	emu.Decode(0xF201)
+	emu.IRegister = 0
+	emu.Decode(0xD011)
+
+	assert.Equal(t, uint8(2), emu.Screen[0])
+
+	emu.Decode(0xF301)
+	emu.Decode(0x00E0)
+
+	assert.Equal(t, uint8(0), emu.Screen[0])
+}
+
+func TestOpcodeDxynPlanes(t *testing.T) {
+	emu := NewEmulator()
+	emu.Machine = MachineXOChip
+	emu.Planes = 3
+	emu.IRegister = 0x300
+	emu.Ram[0x300] = 0x80
+	emu.Ram[0x301] = 0xC0
+	emu.Decode(0xD011)
+
+	assert.Equal(t, uint8(3), emu.Screen[0])
+	assert.Equal(t, uint8(2), emu.Screen[1])
+}
+
+func TestOpcodeF002Fx3A(t *testing.T) {
+	emu := NewEmulator()
+	emu.Machine = MachineXOChip
+	emu.IRegister = 0x300
+	emu.Ram[0x300] = 0xAA
+	emu.Ram[0x30F] = 0x55
+	emu.VRegisters[4] = 100
+	emu.Decode(0xF002)
+	emu.Decode(0xF43A)
+
+	assert.Equal(t, uint8(0xAA), emu.AudioPattern[0])
+	assert.Equal(t, uint8(0x55), emu.AudioPattern[15])
+	assert.Equal(t, uint8(100), emu.Pitch)
+}
+
+func TestMemorySize(t *testing.T) {
+	emu := NewEmulator()
+	assert.Equal(t, CLASSIC_RAM_SIZE, emu.MemorySize())
+
+	emu.Machine = MachineXOChip
+	assert.Equal(t, RAM_SIZE, emu.MemorySize())
+}
+
+func TestOpcodeVFWrittenLast(t *testing.T) {
+	// With x = F the flag is what's left in VF, not the result
+	for _, opcode := range []uint16{0x8F14, 0x8F15, 0x8F16, 0x8F17, 0x8F1E} {
+		emu := NewEmulator()
+		emu.VRegisters[0xF] = 0x81
+		emu.VRegisters[1] = 0x81
+
+		assert.NoError(t, emu.Decode(opcode))
+		assert.LessOrEqual(t, emu.VRegisters[0xF], uint8(1), "%04X", opcode)
+	}
+}
+
+func TestOpcodeUnusedLowDigits(t *testing.T) {
+	for _, opcode := range []uint16{0x5121, 0x9121} {
+		emu := NewEmulator()
+		assert.ErrorIs(t, emu.Decode(opcode), ErrInvalidOpcode, "%04X", opcode)
+	}
+}
+
+func TestDecodeMatchesDecodeInstruction(t *testing.T) {
+	for _, machine := range []Machine{MachineChip8, MachineSuperChip, MachineXOChip} {
+		emu := NewEmulator()
+		emu.Machine = machine
+
+		for opcode := 0; opcode <= 0xFFFF; opcode++ {
+			emu.ProgramCounter = 0x300
+			emu.StackPointer = 1
+			emu.IRegister = 0x400
+
+			op := DecodeInstruction([]byte{uint8(opcode >> 8), uint8(opcode)}, 0).Op
+			valid := op != OpInvalid && op != OpSys && op.Machine() <= machine
+
+			err := emu.Decode(uint16(opcode))
+			if valid == errors.Is(err, ErrInvalidOpcode) {
+				t.Fatalf("%04X on %s: valid is %t, but Decode returned %v", opcode, machine, valid, err)
+			}
+		}
+	}
+}
+
+// opcodeReference is one line of the reference table: what an opcode
+// does to the registers, starting from a program counter of 0x300 and I
+// of 0x400, on CHIP-8 with the default quirks. An expected pc or i of 0
+// means it's unchanged.
+type opcodeReference struct {
+	opcode uint16
+	rule   string
+	setup  func(e *Emulator)
+	pc     uint16
+	i      uint16
+	v      map[uint8]uint8
+}
+
+func registers(values map[uint8]uint8) func(e *Emulator) {
+	return func(e *Emulator) {
+		for r, value := range values {
+			e.VRegisters[r] = value
+		}
+	}
+}
+
+// The behaviour described by Cowgod's Technical Reference and checked by
+// the Timendus test suite
+var opcodeReferences = []opcodeReference{
+	{0x1234, "1nnn jumps to nnn", nil, 0x234, 0, nil},
+	{0x2234, "2nnn calls nnn", nil, 0x234, 0, nil},
+	{0x3112, "3xnn skips when Vx equals nn", registers(map[uint8]uint8{1: 0x12}), 0x302, 0, nil},
+	{0x3113, "3xnn doesn't skip when Vx differs from nn", registers(map[uint8]uint8{1: 0x12}), 0, 0, nil},
+	{0x4113, "4xnn skips when Vx differs from nn", registers(map[uint8]uint8{1: 0x12}), 0x302, 0, nil},
+	{0x5120, "5xy0 skips when Vx equals Vy", registers(map[uint8]uint8{1: 7, 2: 7}), 0x302, 0, nil},
+	{0x61AB, "6xnn loads nn", nil, 0, 0, map[uint8]uint8{1: 0xAB}},
+	{0x7102, "7xnn wraps and leaves VF alone", registers(map[uint8]uint8{1: 0xFF, 0xF: 5}), 0, 0, map[uint8]uint8{1: 1, 0xF: 5}},
+	{0x8120, "8xy0 copies Vy", registers(map[uint8]uint8{2: 9}), 0, 0, map[uint8]uint8{1: 9}},
+	{0x8121, "8xy1 ors", registers(map[uint8]uint8{1: 0x0C, 2: 0x0A, 0xF: 5}), 0, 0, map[uint8]uint8{1: 0x0E, 0xF: 5}},
+	{0x8122, "8xy2 ands", registers(map[uint8]uint8{1: 0x0C, 2: 0x0A}), 0, 0, map[uint8]uint8{1: 0x08}},
+	{0x8123, "8xy3 xors", registers(map[uint8]uint8{1: 0x0C, 2: 0x0A}), 0, 0, map[uint8]uint8{1: 0x06}},
+	{0x8124, "8xy4 adds with no carry", registers(map[uint8]uint8{1: 0xF0, 2: 0x0F}), 0, 0, map[uint8]uint8{1: 0xFF, 0xF: 0}},
+	{0x8124, "8xy4 carries", registers(map[uint8]uint8{1: 0xF0, 2: 0x11}), 0, 0, map[uint8]uint8{1: 0x01, 0xF: 1}},
+	{0x8F14, "8xy4 with x = F leaves the carry in VF", registers(map[uint8]uint8{0xF: 0xFF, 1: 2}), 0, 0, map[uint8]uint8{0xF: 1}},
+	{0x8125, "8xy5 subtracts with no borrow", registers(map[uint8]uint8{1: 5, 2: 3}), 0, 0, map[uint8]uint8{1: 2, 0xF: 1}},
+	{0x8125, "8xy5 doesn't borrow for equal values", registers(map[uint8]uint8{1: 5, 2: 5}), 0, 0, map[uint8]uint8{1: 0, 0xF: 1}},
+	{0x8125, "8xy5 borrows and wraps", registers(map[uint8]uint8{1: 3, 2: 5}), 0, 0, map[uint8]uint8{1: 0xFE, 0xF: 0}},
+	{0x8126, "8xy6 shifts Vx right", registers(map[uint8]uint8{1: 5, 2: 0xF0}), 0, 0, map[uint8]uint8{1: 2, 0xF: 1}},
+	{0x8F06, "8xy6 with x = F leaves the shifted bit in VF", registers(map[uint8]uint8{0xF: 2}), 0, 0, map[uint8]uint8{0xF: 0}},
+	{0x8127, "8xy7 subtracts Vx from Vy", registers(map[uint8]uint8{1: 3, 2: 5}), 0, 0, map[uint8]uint8{1: 2, 0xF: 1}},
+	{0x8127, "8xy7 borrows and wraps", registers(map[uint8]uint8{1: 5, 2: 3}), 0, 0, map[uint8]uint8{1: 0xFE, 0xF: 0}},
+	{0x812E, "8xyE shifts Vx left", registers(map[uint8]uint8{1: 0x81}), 0, 0, map[uint8]uint8{1: 0x02, 0xF: 1}},
+	{0x9120, "9xy0 skips when Vx differs from Vy", registers(map[uint8]uint8{1: 7, 2: 8}), 0x302, 0, nil},
+	{0xA123, "Annn loads I", nil, 0, 0x123, nil},
+	{0xB123, "Bnnn jumps to nnn + V0", registers(map[uint8]uint8{0: 0x10, 1: 0x20}), 0x133, 0, nil},
+	{0xE19E, "Ex9E doesn't skip when the key is up", registers(map[uint8]uint8{1: 4}), 0, 0, nil},
+	{0xE1A1, "ExA1 skips when the key is up", registers(map[uint8]uint8{1: 4}), 0x302, 0, nil},
+	{0xF107, "Fx07 reads the delay timer", func(e *Emulator) { e.DelayTimer = 42 }, 0, 0, map[uint8]uint8{1: 42}},
+	{0xF10A, "Fx0A waits for a key", nil, 0x2FE, 0, nil},
+	{0xF11E, "Fx1E adds Vx to I", registers(map[uint8]uint8{1: 0x10, 0xF: 5}), 0, 0x410, map[uint8]uint8{0xF: 5}},
+	{0xF129, "Fx29 points I at the digit in Vx", registers(map[uint8]uint8{1: 0xA}), 0, 50, nil},
+	{0xF265, "Fx65 loads V0 to Vx and leaves I alone", func(e *Emulator) { e.Ram[0x400], e.Ram[0x402] = 1, 3 }, 0, 0, map[uint8]uint8{0: 1, 2: 3}},
+}
+
+func TestOpcodeReference(t *testing.T) {
+	for _, ref := range opcodeReferences {
+		t.Run(fmt.Sprintf("%04X %s", ref.opcode, ref.rule), func(t *testing.T) {
+			emu := NewEmulator()
+			emu.ProgramCounter = 0x300
+			emu.IRegister = 0x400
+			if ref.setup != nil {
+				ref.setup(&emu)
+			}
+			before := emu.VRegisters
+
+			assert.NoError(t, emu.Decode(ref.opcode))
+
+			want_pc, want_i := ref.pc, ref.i
+			if want_pc == 0 {
+				want_pc = 0x300
+			}
+			if want_i == 0 {
+				want_i = 0x400
+			}
+			assert.Equal(t, want_pc, emu.ProgramCounter, "program counter")
+			assert.Equal(t, want_i, emu.IRegister, "I")
+
+			// Registers not listed keep their values
+			want := before
+			for r, value := range ref.v {
+				want[r] = value
+			}
+			assert.Equal(t, want, emu.VRegisters)
+		})
+	}
+}
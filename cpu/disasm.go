@@ -0,0 +1,236 @@
+package cpu
+
+import (
+	"fmt"
+)
+
+// Disassemble turns the instruction at address into a Cowgod style
+// mnemonic, such as "LD V2, 0x1F". It returns the instruction's size in
+// bytes, which is 4 for the XO-CHIP F000 NNNN and 2 for everything else.
+func Disassemble(ram []byte, address uint16) (string, uint16) {
+	i := DecodeInstruction(ram, address)
+	return i.Cowgod(), i.Size()
+}
+
+// Cowgod formats the instruction in the syntax of Cowgod's CHIP-8
+// technical reference, with the SUPER-CHIP and XO-CHIP additions.
+// Opcodes that aren't instructions on any machine come back as "DW".
+func (i Instruction) Cowgod() string {
+	switch i.Op {
+	case OpSys:
+		return fmt.Sprintf("SYS 0x%03X", i.NNN)
+	case OpClear:
+		return "CLS"
+	case OpReturn:
+		return "RET"
+	case OpScrollDown:
+		return fmt.Sprintf("SCD %d", i.N)
+	case OpScrollUp:
+		return fmt.Sprintf("SCU %d", i.N)
+	case OpScrollRight:
+		return "SCR"
+	case OpScrollLeft:
+		return "SCL"
+	case OpExit:
+		return "EXIT"
+	case OpLowRes:
+		return "LOW"
+	case OpHighRes:
+		return "HIGH"
+	case OpJump:
+		return fmt.Sprintf("JP 0x%03X", i.NNN)
+	case OpCall:
+		return fmt.Sprintf("CALL 0x%03X", i.NNN)
+	case OpSkipEqualByte:
+		return fmt.Sprintf("SE V%X, 0x%02X", i.X, i.NN)
+	case OpSkipNotEqualByte:
+		return fmt.Sprintf("SNE V%X, 0x%02X", i.X, i.NN)
+	case OpSkipEqual:
+		return fmt.Sprintf("SE V%X, V%X", i.X, i.Y)
+	case OpSaveRange:
+		return fmt.Sprintf("SAVE V%X, V%X", i.X, i.Y)
+	case OpLoadRange:
+		return fmt.Sprintf("LOAD V%X, V%X", i.X, i.Y)
+	case OpLoadByte:
+		return fmt.Sprintf("LD V%X, 0x%02X", i.X, i.NN)
+	case OpAddByte:
+		return fmt.Sprintf("ADD V%X, 0x%02X", i.X, i.NN)
+	case OpLoad:
+		return fmt.Sprintf("LD V%X, V%X", i.X, i.Y)
+	case OpOr:
+		return fmt.Sprintf("OR V%X, V%X", i.X, i.Y)
+	case OpAnd:
+		return fmt.Sprintf("AND V%X, V%X", i.X, i.Y)
+	case OpXor:
+		return fmt.Sprintf("XOR V%X, V%X", i.X, i.Y)
+	case OpAdd:
+		return fmt.Sprintf("ADD V%X, V%X", i.X, i.Y)
+	case OpSub:
+		return fmt.Sprintf("SUB V%X, V%X", i.X, i.Y)
+	case OpShiftRight:
+		return fmt.Sprintf("SHR V%X, V%X", i.X, i.Y)
+	case OpSubN:
+		return fmt.Sprintf("SUBN V%X, V%X", i.X, i.Y)
+	case OpShiftLeft:
+		return fmt.Sprintf("SHL V%X, V%X", i.X, i.Y)
+	case OpSkipNotEqual:
+		return fmt.Sprintf("SNE V%X, V%X", i.X, i.Y)
+	case OpLoadI:
+		return fmt.Sprintf("LD I, 0x%03X", i.NNN)
+	case OpJumpV0:
+		return fmt.Sprintf("JP V0, 0x%03X", i.NNN)
+	case OpRandom:
+		return fmt.Sprintf("RND V%X, 0x%02X", i.X, i.NN)
+	case OpDraw:
+		return fmt.Sprintf("DRW V%X, V%X, %d", i.X, i.Y, i.N)
+	case OpSkipKey:
+		return fmt.Sprintf("SKP V%X", i.X)
+	case OpSkipNotKey:
+		return fmt.Sprintf("SKNP V%X", i.X)
+	case OpLoadLongI:
+		return fmt.Sprintf("LD I, LONG 0x%04X", i.Long)
+	case OpPlane:
+		return fmt.Sprintf("PLANE %d", i.X)
+	case OpAudio:
+		return "AUDIO"
+	case OpGetDelay:
+		return fmt.Sprintf("LD V%X, DT", i.X)
+	case OpWaitKey:
+		return fmt.Sprintf("LD V%X, K", i.X)
+	case OpSetDelay:
+		return fmt.Sprintf("LD DT, V%X", i.X)
+	case OpSetSound:
+		return fmt.Sprintf("LD ST, V%X", i.X)
+	case OpAddI:
+		return fmt.Sprintf("ADD I, V%X", i.X)
+	case OpFont:
+		return fmt.Sprintf("LD F, V%X", i.X)
+	case OpBigFont:
+		return fmt.Sprintf("LD HF, V%X", i.X)
+	case OpBCD:
+		return fmt.Sprintf("LD B, V%X", i.X)
+	case OpPitch:
+		return fmt.Sprintf("PITCH V%X", i.X)
+	case OpSaveRegisters:
+		return fmt.Sprintf("LD [I], V%X", i.X)
+	case OpLoadRegisters:
+		return fmt.Sprintf("LD V%X, [I]", i.X)
+	case OpSaveFlags:
+		return fmt.Sprintf("LD R, V%X", i.X)
+	case OpLoadFlags:
+		return fmt.Sprintf("LD V%X, R", i.X)
+	}
+
+	return fmt.Sprintf("DW 0x%04X", i.Opcode)
+}
+
+// Octo formats the instruction the way the Octo assembler writes it. The
+// skip instructions come out as the "if ... then" that Octo uses for them.
+func (i Instruction) Octo() string {
+	switch i.Op {
+	case OpSys:
+		return fmt.Sprintf("native 0x%03X", i.NNN)
+	case OpClear:
+		return "clear"
+	case OpReturn:
+		return "return"
+	case OpScrollDown:
+		return fmt.Sprintf("scroll-down %d", i.N)
+	case OpScrollUp:
+		return fmt.Sprintf("scroll-up %d", i.N)
+	case OpScrollRight:
+		return "scroll-right"
+	case OpScrollLeft:
+		return "scroll-left"
+	case OpExit:
+		return "exit"
+	case OpLowRes:
+		return "lores"
+	case OpHighRes:
+		return "hires"
+	case OpJump:
+		return fmt.Sprintf("jump 0x%03X", i.NNN)
+	case OpCall:
+		return fmt.Sprintf(":call 0x%03X", i.NNN)
+	case OpSkipEqualByte:
+		return fmt.Sprintf("if v%x != 0x%02X then", i.X, i.NN)
+	case OpSkipNotEqualByte:
+		return fmt.Sprintf("if v%x == 0x%02X then", i.X, i.NN)
+	case OpSkipEqual:
+		return fmt.Sprintf("if v%x != v%x then", i.X, i.Y)
+	case OpSaveRange:
+		return fmt.Sprintf("save v%x - v%x", i.X, i.Y)
+	case OpLoadRange:
+		return fmt.Sprintf("load v%x - v%x", i.X, i.Y)
+	case OpLoadByte:
+		return fmt.Sprintf("v%x := 0x%02X", i.X, i.NN)
+	case OpAddByte:
+		return fmt.Sprintf("v%x += 0x%02X", i.X, i.NN)
+	case OpLoad:
+		return fmt.Sprintf("v%x := v%x", i.X, i.Y)
+	case OpOr:
+		return fmt.Sprintf("v%x |= v%x", i.X, i.Y)
+	case OpAnd:
+		return fmt.Sprintf("v%x &= v%x", i.X, i.Y)
+	case OpXor:
+		return fmt.Sprintf("v%x ^= v%x", i.X, i.Y)
+	case OpAdd:
+		return fmt.Sprintf("v%x += v%x", i.X, i.Y)
+	case OpSub:
+		return fmt.Sprintf("v%x -= v%x", i.X, i.Y)
+	case OpShiftRight:
+		return fmt.Sprintf("v%x >>= v%x", i.X, i.Y)
+	case OpSubN:
+		return fmt.Sprintf("v%x =- v%x", i.X, i.Y)
+	case OpShiftLeft:
+		return fmt.Sprintf("v%x <<= v%x", i.X, i.Y)
+	case OpSkipNotEqual:
+		return fmt.Sprintf("if v%x == v%x then", i.X, i.Y)
+	case OpLoadI:
+		return fmt.Sprintf("i := 0x%03X", i.NNN)
+	case OpJumpV0:
+		return fmt.Sprintf("jump0 0x%03X", i.NNN)
+	case OpRandom:
+		return fmt.Sprintf("v%x := random 0x%02X", i.X, i.NN)
+	case OpDraw:
+		return fmt.Sprintf("sprite v%x v%x %d", i.X, i.Y, i.N)
+	case OpSkipKey:
+		return fmt.Sprintf("if v%x -key then", i.X)
+	case OpSkipNotKey:
+		return fmt.Sprintf("if v%x key then", i.X)
+	case OpLoadLongI:
+		return fmt.Sprintf("i := long 0x%04X", i.Long)
+	case OpPlane:
+		return fmt.Sprintf("plane %d", i.X)
+	case OpAudio:
+		return "audio"
+	case OpGetDelay:
+		return fmt.Sprintf("v%x := delay", i.X)
+	case OpWaitKey:
+		return fmt.Sprintf("v%x := key", i.X)
+	case OpSetDelay:
+		return fmt.Sprintf("delay := v%x", i.X)
+	case OpSetSound:
+		return fmt.Sprintf("buzzer := v%x", i.X)
+	case OpAddI:
+		return fmt.Sprintf("i += v%x", i.X)
+	case OpFont:
+		return fmt.Sprintf("i := hex v%x", i.X)
+	case OpBigFont:
+		return fmt.Sprintf("i := bighex v%x", i.X)
+	case OpBCD:
+		return fmt.Sprintf("bcd v%x", i.X)
+	case OpPitch:
+		return fmt.Sprintf("pitch := v%x", i.X)
+	case OpSaveRegisters:
+		return fmt.Sprintf("save v%x", i.X)
+	case OpLoadRegisters:
+		return fmt.Sprintf("load v%x", i.X)
+	case OpSaveFlags:
+		return fmt.Sprintf("saveflags v%x", i.X)
+	case OpLoadFlags:
+		return fmt.Sprintf("loadflags v%x", i.X)
+	}
+
+	return fmt.Sprintf("0x%02X 0x%02X", i.Opcode>>8, i.Opcode&0xFF)
+}
@@ -1,164 +1,566 @@
+//go:build !nosdl
+
 package cpu
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"io/fs"
+	"os"
+
 	"github.com/veandco/go-sdl2/sdl"
 )
 
+var stateSlots = map[sdl.Keycode]int{
+	sdl.K_F1: 1,
+	sdl.K_F2: 2,
+	sdl.K_F3: 3,
+	sdl.K_F4: 4,
+	sdl.K_F5: 5,
+	sdl.K_F6: 6,
+	sdl.K_F7: 7,
+	sdl.K_F8: 8,
+	sdl.K_F9: 9,
+}
+
+// Display is the SDL window frontend. Ctrl+M mutes the buzzer. F1 to F9
+// load a save state slot and Shift+F1 to F9 save one, when StatePath is set.
+// Ctrl+P pauses, holding Ctrl+Tab fast-forwards, and Ctrl+- and Ctrl+=
+// change the speed. F12 saves a screenshot when ScreenshotPath is set, and
+// Ctrl+T cycles through the themes. The hotkeys without Ctrl are free for
+// the keymap, but the F keys can't be bound. The speed can't be changed and
+// states can't be loaded while a movie is recorded or replayed.
 type Display struct {
+	Beeper Beeper
+
+	// Timing and InstructionsPerSecond are passed on to the Runner, 0
+	// instructions per second means DEFAULT_IPS
+	Timing                Timing
+	InstructionsPerSecond int
+
+	// Keymap defaults to the qwerty preset
+	Keymap Keymap
+
+	// StatePath is the prefix for save state files, slot 1 is saved to
+	// StatePath + ".state1"
+	StatePath string
+
+	// Record, Replay, Capture and Debug are passed on to the Runner. The
+	// window stops responding while Debug runs.
+	Record  *Movie
+	Replay  *Movie
+	Capture *Capture
+	Debug   func(emulator *Emulator)
+
+	// ScreenshotPath is the prefix for screenshots taken with F12, the
+	// first is saved to ScreenshotPath + "-1.png". Screenshot, when set,
+	// gets the last frame when the window closes. Both are
+	// ScreenshotScale image pixels to a CHIP-8 pixel.
+	ScreenshotPath  string
+	Screenshot      io.Writer
+	ScreenshotScale int
+
+	// Title is shown in the window title bar after "CHIP-8"
+	Title string
+
+	// Colors are the background and pixel colors, indexed by pixel value.
+	// Values past the end use the colors of the green theme.
+	Colors []color.RGBA
+
+	// Scale is the size of a pixel in the window as it opens, 0 means
+	// SCREEN_SCALE. The window can be resized after, and Scaling decides
+	// how the screen fills it.
+	Scale   int
+	Scaling Scaling
+
+	renderer *sdl.Renderer
+	palette  color.Palette
+	audio    sdl.AudioDeviceID
+	emulator *Emulator
+	runner   *Runner
+	tone     bool
+
+	keys        map[sdl.Keycode]uint8
+	buttons     map[sdl.GameControllerButton]uint8
+	controllers map[sdl.JoystickID]*sdl.GameController
+
+	// err is an SDL failure from inside the frame loop, which stops it
+	err error
 }
 
-func (d *Display) Run(emulator Emulator) {
+// Run opens the window and runs the emulator until the user closes it. It
+// returns SDL failures and errors from the ROM, and leaves the emulator as
+// it stopped.
+func (d *Display) Run(emulator *Emulator) error {
 	if err := sdl.Init(sdl.INIT_EVERYTHING); err != nil {
-		panic(err)
+		return err
 	}
 	defer sdl.Quit()
 
+	if err := d.resolveKeymap(); err != nil {
+		return err
+	}
+	defer d.closeControllers()
+
+	title := "CHIP-8"
+	if d.Title != "" {
+		title += " - " + d.Title
+	}
+
+	scale := int32(d.Scale)
+	if scale <= 0 {
+		scale = int32(SCREEN_SCALE)
+	}
+
 	window, err := sdl.CreateWindow(
-		"CHIP-8",
+		title,
 		sdl.WINDOWPOS_UNDEFINED,
 		sdl.WINDOWPOS_UNDEFINED,
-		int32(SCREEN_WIDTH*SCREEN_SCALE),
-		int32(SCREEN_HEIGHT*SCREEN_SCALE),
-		sdl.WINDOW_SHOWN,
+		int32(SCREEN_WIDTH)*scale,
+		int32(SCREEN_HEIGHT)*scale,
+		sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE,
 	)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer window.Destroy()
 
 	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer renderer.Destroy()
 
-	setBackgroundColor(renderer)
+	d.renderer = renderer
+
+	if d.Beeper.SampleRate == 0 {
+		d.Beeper = NewBeeper()
+	}
+
+	spec := sdl.AudioSpec{
+		Freq:     int32(d.Beeper.SampleRate),
+		Format:   sdl.AUDIO_S16LSB,
+		Channels: 1,
+		Samples:  512,
+	}
+
+	audio, err := sdl.OpenAudioDevice("", false, &spec, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer sdl.CloseAudioDevice(audio)
+
+	sdl.PauseAudioDevice(audio, false)
+	d.audio = audio
+	d.emulator = emulator
+	d.palette = screenPalette(d.Colors)
+
+	d.setPixelColor(renderer, 0)
 	renderer.Clear()
 
-	running := true
-	for running {
-		for i := 0; i < 10; i++ {
-			emulator.Tick()
+	runner := NewRunner(d)
+	runner.Timing = d.Timing
+	if d.InstructionsPerSecond > 0 {
+		runner.InstructionsPerSecond = d.InstructionsPerSecond
+	}
+	runner.Record = d.Record
+	runner.Replay = d.Replay
+	runner.Capture = d.Capture
+	runner.Debug = d.Debug
+	d.runner = &runner
+	if err := runner.Run(emulator); err != nil {
+		return err
+	}
+
+	if d.Screenshot != nil {
+		if err := WriteScreenshot(d.Screenshot, emulator.Screen, emulator.ScreenWidth, emulator.ScreenHeight, d.ScreenshotScale, d.Colors); err != nil {
+			return err
 		}
+	}
+
+	return d.err
+}
 
-		emulator.TickTimers()
-		d.DrawScreen(renderer, emulator.Screen)
+func (d *Display) Present(screen []uint8, width uint16, height uint16) {
+	if d.tone {
+		d.queueTone()
+	}
+
+	d.DrawScreen(d.renderer, screen, width, height)
+}
 
-		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-			switch t := event.(type) {
-			case *sdl.QuitEvent:
-				running = false
+func (d *Display) PollInput(emulator *Emulator) bool {
+	running := d.err == nil
+
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		switch t := event.(type) {
+		case *sdl.QuitEvent:
+			running = false
+
+		case *sdl.KeyboardEvent:
+			if d.hotkey(emulator, t) {
 				break
+			}
+
+			if key, ok := d.keys[t.Keysym.Sym]; ok {
+				pressKey(emulator, key, t.State)
+			}
 
-			case *sdl.KeyboardEvent:
-				switch t.State {
-				case sdl.RELEASED:
-					switch t.Keysym.Sym {
-					case sdl.K_1:
-						emulator.Key(0x1, 0)
-					case sdl.K_2:
-						emulator.Key(0x2, 0)
-					case sdl.K_3:
-						emulator.Key(0x3, 0)
-					case sdl.K_4:
-						emulator.Key(0xC, 0)
-					case sdl.K_q:
-						emulator.Key(0x4, 0)
-					case sdl.K_w:
-						emulator.Key(0x5, 0)
-					case sdl.K_e:
-						emulator.Key(0x6, 0)
-					case sdl.K_r:
-						emulator.Key(0xD, 0)
-					case sdl.K_a:
-						emulator.Key(0x7, 0)
-					case sdl.K_s:
-						emulator.Key(0x8, 0)
-					case sdl.K_d:
-						emulator.Key(0x9, 0)
-					case sdl.K_f:
-						emulator.Key(0xE, 0)
-					case sdl.K_z:
-						emulator.Key(0xA, 0)
-					case sdl.K_x:
-						emulator.Key(0xB, 0)
-					case sdl.K_c:
-						emulator.Key(0x0, 0)
-					case sdl.K_v:
-						emulator.Key(0xf, 0)
-					}
-
-				case sdl.PRESSED:
-					switch t.Keysym.Sym {
-					case sdl.K_1:
-						emulator.Key(0x1, 1)
-					case sdl.K_2:
-						emulator.Key(0x2, 1)
-					case sdl.K_3:
-						emulator.Key(0x3, 1)
-					case sdl.K_4:
-						emulator.Key(0xC, 1)
-					case sdl.K_q:
-						emulator.Key(0x4, 1)
-					case sdl.K_w:
-						emulator.Key(0x5, 1)
-					case sdl.K_e:
-						emulator.Key(0x6, 1)
-					case sdl.K_r:
-						emulator.Key(0xD, 1)
-					case sdl.K_a:
-						emulator.Key(0x7, 1)
-					case sdl.K_s:
-						emulator.Key(0x8, 1)
-					case sdl.K_d:
-						emulator.Key(0x9, 1)
-					case sdl.K_f:
-						emulator.Key(0xE, 1)
-					case sdl.K_z:
-						emulator.Key(0xA, 1)
-					case sdl.K_x:
-						emulator.Key(0xB, 1)
-					case sdl.K_c:
-						emulator.Key(0x0, 1)
-					case sdl.K_v:
-						emulator.Key(0xf, 1)
-					}
+		case *sdl.ControllerButtonEvent:
+			if key, ok := d.buttons[sdl.GameControllerButton(t.Button)]; ok {
+				pressKey(emulator, key, t.State)
+			}
+
+		case *sdl.ControllerDeviceEvent:
+			switch t.Type {
+			case sdl.CONTROLLERDEVICEADDED:
+				if controller := sdl.GameControllerOpen(int(t.Which)); controller != nil {
+					d.controllers[controller.Joystick().InstanceID()] = controller
+				}
+
+			case sdl.CONTROLLERDEVICEREMOVED:
+				if controller, ok := d.controllers[t.Which]; ok {
+					controller.Close()
+					delete(d.controllers, t.Which)
 				}
 			}
 		}
+	}
+
+	return running
+}
+
+// hotkey handles the emulator's own keys. It returns false for any other
+// key, and for keys without Ctrl other than the F keys, so the keymap gets
+// those.
+func (d *Display) hotkey(emulator *Emulator, event *sdl.KeyboardEvent) bool {
+	pressed := event.State == sdl.PRESSED
+	sym := event.Keysym.Sym
+
+	if sym == sdl.K_F12 {
+		if pressed {
+			d.saveScreenshot(emulator)
+		}
+		return true
+	}
+
+	if slot, ok := stateSlots[sym]; ok {
+		if pressed && event.Keysym.Mod&sdl.KMOD_SHIFT != 0 {
+			d.saveState(emulator, slot)
+		} else if pressed {
+			d.loadState(emulator, slot)
+		}
+		return true
+	}
+
+	// Letting go of Tab stops fast-forward even if Ctrl was let go first
+	if sym == sdl.K_TAB && !pressed {
+		d.runner.FastForward = false
+	}
+
+	if event.Keysym.Mod&sdl.KMOD_CTRL == 0 {
+		return false
+	}
+
+	switch sym {
+	case sdl.K_m:
+		if pressed {
+			d.Beeper.Muted = !d.Beeper.Muted
+			sdl.ClearQueuedAudio(d.audio)
+		}
+
+	case sdl.K_t:
+		if pressed {
+			d.nextTheme()
+		}
+
+	default:
+		return d.timingHotkey(event)
+	}
+
+	return true
+}
+
+// timingHotkey handles the pause, fast-forward and speed keys. It returns
+// false for any other key.
+func (d *Display) timingHotkey(event *sdl.KeyboardEvent) bool {
+	pressed := event.State == sdl.PRESSED
+
+	switch event.Keysym.Sym {
+	case sdl.K_TAB:
+		d.runner.FastForward = pressed
+
+	case sdl.K_p:
+		if pressed && event.Repeat == 0 {
+			d.runner.Paused = !d.runner.Paused
+			if d.runner.Paused {
+				fmt.Fprintln(os.Stderr, "Paused")
+			} else {
+				fmt.Fprintln(os.Stderr, "Resumed")
+			}
+		}
+
+	case sdl.K_EQUALS, sdl.K_MINUS:
+		if !pressed {
+			break
+		}
+
+		if d.runner.Timing == TimingVIP {
+			fmt.Fprintln(os.Stderr, "The speed is set by VIP timing")
+			break
+		}
+
+		if d.moviePlaying() {
+			fmt.Fprintln(os.Stderr, "Can't change the speed while recording or replaying a movie")
+			break
+		}
+
+		if event.Keysym.Sym == sdl.K_EQUALS {
+			d.runner.SpeedUp()
+		} else {
+			d.runner.SpeedDown()
+		}
+		fmt.Fprintf(os.Stderr, "%d instructions per second\n", d.runner.InstructionsPerSecond)
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+// moviePlaying says whether a movie is being recorded or replayed. A movie
+// only holds the speed and state it started with, so changing either would
+// make it replay differently.
+func (d *Display) moviePlaying() bool {
+	return d.Record != nil || d.Replay != nil
+}
+
+func pressKey(emulator *Emulator, key uint8, state uint8) {
+	switch state {
+	case sdl.RELEASED:
+		emulator.Key(key, 0)
+
+	case sdl.PRESSED:
+		emulator.Key(key, 1)
+	}
+}
+
+// resolveKeymap looks up the SDL codes for the names in Keymap.
+func (d *Display) resolveKeymap() error {
+	if d.Keymap.Keys == nil {
+		d.Keymap, _ = KeymapByName("qwerty")
+	}
+
+	d.keys = map[sdl.Keycode]uint8{}
+	for name, key := range d.Keymap.Keys {
+		code := sdl.GetKeyFromName(name)
+		if code == sdl.K_UNKNOWN {
+			return fmt.Errorf("unknown key name %q", name)
+		}
+
+		if _, ok := stateSlots[code]; ok || code == sdl.K_F12 {
+			return fmt.Errorf("%s is a hotkey and can't be bound to a CHIP-8 key", name)
+		}
+
+		d.keys[code] = key
+	}
+
+	d.buttons = map[sdl.GameControllerButton]uint8{}
+	for name, key := range d.Keymap.Buttons {
+		button := sdl.GameControllerGetButtonFromString(name)
+		if button == sdl.CONTROLLER_BUTTON_INVALID {
+			return fmt.Errorf("unknown controller button %q", name)
+		}
+
+		d.buttons[button] = key
+	}
+
+	d.controllers = map[sdl.JoystickID]*sdl.GameController{}
+
+	return nil
+}
 
-		sdl.Delay(1000 / 60)
+func (d *Display) closeControllers() {
+	for _, controller := range d.controllers {
+		controller.Close()
 	}
 }
 
-func (d *Display) DrawScreen(renderer *sdl.Renderer, screen [SCREEN_TOTAL]uint8) {
-	setBackgroundColor(renderer)
+func (d *Display) PlayTone(on bool) {
+	d.tone = on
+
+	if on {
+		d.Beeper.Reset()
+		d.queueTone()
+	} else {
+		sdl.ClearQueuedAudio(d.audio)
+	}
+}
+
+// queueTone keeps two frames of the tone queued, so it plays without gaps
+// until the next frame tops it up and stops within a frame of PlayTone(false).
+func (d *Display) queueTone() {
+	if d.emulator.Machine >= MachineXOChip {
+		d.Beeper.SetPattern(d.emulator.AudioPattern, d.emulator.Pitch)
+	}
+
+	frame := d.Beeper.SampleRate / 60
+	queued := int(sdl.GetQueuedAudioSize(d.audio)) / 2
+	if queued >= 2*frame {
+		return
+	}
+
+	samples := make([]int16, 2*frame-queued)
+	d.Beeper.Fill(samples)
+
+	data := make([]byte, len(samples)*2)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(sample))
+	}
+
+	if err := sdl.QueueAudio(d.audio, data); err != nil {
+		d.err = err
+	}
+}
+
+func (d *Display) saveState(emulator *Emulator, slot int) {
+	if d.StatePath == "" {
+		return
+	}
+
+	path := fmt.Sprintf("%s.state%d", d.StatePath, slot)
+
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not save state: %v\n", err)
+		return
+	}
+	defer file.Close()
+
+	if err := emulator.SaveState(file); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not save state: %v\n", err)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Saved state to %s\n", path)
+}
+
+func (d *Display) loadState(emulator *Emulator, slot int) {
+	if d.StatePath == "" {
+		return
+	}
+
+	if d.moviePlaying() {
+		fmt.Fprintln(os.Stderr, "Can't load a state while recording or replaying a movie")
+		return
+	}
+
+	path := fmt.Sprintf("%s.state%d", d.StatePath, slot)
+
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not load state: %v\n", err)
+		return
+	}
+	defer file.Close()
+
+	if err := emulator.LoadState(file); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not load state %s: %v\n", path, err)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Loaded state from %s\n", path)
+}
+
+// nextTheme switches to the next theme in ThemeNames.
+func (d *Display) nextTheme() {
+	name := themeAfter(d.Colors)
+
+	d.Colors = themes[name]
+	d.palette = screenPalette(d.Colors)
+	fmt.Fprintf(os.Stderr, "Theme %s\n", name)
+}
+
+// saveScreenshot saves the screen to the first ScreenshotPath-N.png that
+// doesn't exist yet.
+func (d *Display) saveScreenshot(emulator *Emulator) {
+	if d.ScreenshotPath == "" {
+		return
+	}
+
+	var file *os.File
+	var err error
+
+	for n := 1; ; n++ {
+		path := fmt.Sprintf("%s-%d.png", d.ScreenshotPath, n)
+
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if !errors.Is(err, fs.ErrExist) {
+			break
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not save screenshot: %v\n", err)
+		return
+	}
+	defer file.Close()
+
+	if err := WriteScreenshot(file, emulator.Screen, emulator.ScreenWidth, emulator.ScreenHeight, d.ScreenshotScale, d.Colors); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not save screenshot: %v\n", err)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Saved screenshot to %s\n", file.Name())
+}
+
+// DrawScreen fits the screen to the window as Scaling says, so a 128x64
+// SUPER-CHIP screen takes up the same space as a 64x32 one.
+func (d *Display) DrawScreen(renderer *sdl.Renderer, screen []uint8, width uint16, height uint16) {
+	d.setPixelColor(renderer, 0)
 	renderer.Clear()
 
+	window_width, window_height, err := renderer.GetOutputSize()
+	if err != nil {
+		d.err = err
+		return
+	}
+
+	area_left, area_top, area_width, area_height := screenArea(int(window_width), int(window_height), int(width), int(height), d.Scaling)
+
 	for i, v := range screen {
-		rect := sdl.Rect{
-			X: (int32(i) % int32(SCREEN_WIDTH)) * int32(SCREEN_SCALE),
-			Y: (int32(i) / int32(SCREEN_WIDTH)) * int32(SCREEN_SCALE),
-			W: int32(SCREEN_SCALE),
-			H: int32(SCREEN_SCALE),
+		// The background is already there
+		if v&0x3 == 0 {
+			continue
 		}
 
-		if v == 1 {
-			setForegroundColor(renderer)
-		} else {
-			setBackgroundColor(renderer)
+		x := i % int(width)
+		y := i / int(width)
+
+		left := area_left + x*area_width/int(width)
+		top := area_top + y*area_height/int(height)
+
+		rect := sdl.Rect{
+			X: int32(left),
+			Y: int32(top),
+			W: int32(area_left + (x+1)*area_width/int(width) - left),
+			H: int32(area_top + (y+1)*area_height/int(height) - top),
 		}
 
+		d.setPixelColor(renderer, v)
+
 		renderer.FillRect(&rect)
 	}
 
 	renderer.Present()
 }
 
-func setBackgroundColor(renderer *sdl.Renderer) {
-	renderer.SetDrawColor(0, 0, 0, 0)
-}
-
-func setForegroundColor(renderer *sdl.Renderer) {
-	renderer.SetDrawColor(15, 255, 80, 255)
+// Pixels are 0 or 1, or a 2-bit plane mask on XO-CHIP
+func (d *Display) setPixelColor(renderer *sdl.Renderer, pixel uint8) {
+	c := d.palette[pixel&0x3].(color.RGBA)
+	renderer.SetDrawColor(c.R, c.G, c.B, 255)
 }
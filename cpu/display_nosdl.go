@@ -0,0 +1,35 @@
+//go:build nosdl
+
+package cpu
+
+import (
+	"errors"
+	"image/color"
+	"io"
+)
+
+// Display is unavailable when built with the nosdl tag. Use Headless.
+type Display struct {
+	Beeper                Beeper
+	Timing                Timing
+	InstructionsPerSecond int
+	StatePath             string
+	Keymap                Keymap
+	Record                *Movie
+	Replay                *Movie
+	Capture               *Capture
+	Debug                 func(emulator *Emulator)
+	ScreenshotPath        string
+	Screenshot            io.Writer
+	ScreenshotScale       int
+	Title                 string
+	Colors                []color.RGBA
+	Scale                 int
+	Scaling               Scaling
+}
+
+var ErrNoSDL = errors.New("chip-8 was built without SDL support; run with -headless")
+
+func (d *Display) Run(emulator *Emulator) error {
+	return ErrNoSDL
+}
@@ -1,18 +1,36 @@
 package cpu
 
 import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
 	"os"
+	"time"
 )
 
 const (
-	RAM_SIZE       uint16 = 4096
-	SCREEN_WIDTH   uint16 = 64
-	SCREEN_HEIGHT  uint16 = 32
-	SCREEN_TOTAL   uint16 = SCREEN_WIDTH * SCREEN_HEIGHT
-	SCREEN_SCALE   uint16 = 15
-	REGISTER_COUNT uint8  = 16
-	STACK_SIZE     uint8  = 16
-	START_ADDRESS  uint16 = 512
+	RAM_SIZE            uint32 = 65536
+	CLASSIC_RAM_SIZE    uint32 = 4096
+	SCREEN_WIDTH        uint16 = 64
+	SCREEN_HEIGHT       uint16 = 32
+	SCREEN_TOTAL        uint16 = SCREEN_WIDTH * SCREEN_HEIGHT
+	SCREEN_SCALE        uint16 = 15
+	HIRES_SCREEN_WIDTH  uint16 = 128
+	HIRES_SCREEN_HEIGHT uint16 = 64
+	REGISTER_COUNT      uint8  = 16
+	STACK_SIZE          uint8  = 16
+	START_ADDRESS       uint16 = 512
+	BIG_FONT_ADDRESS    uint16 = 80
+	RPL_FLAG_COUNT      uint8  = 16
+	AUDIO_PATTERN_SIZE  uint8  = 16
+	DEFAULT_PITCH       uint8  = 64
+)
+
+var (
+	ErrRomTooLarge    = errors.New("ROM is too large")
+	ErrStackOverflow  = errors.New("stack overflow")
+	ErrStackUnderflow = errors.New("stack underflow")
+	ErrInvalidOpcode  = errors.New("invalid opcode")
 )
 
 var fontSet = []uint8{
@@ -34,10 +52,32 @@ var fontSet = []uint8{
 	0xF0, 0x80, 0xF0, 0x80, 0x80, // F
 }
 
+// The SUPER-CHIP 8x10 digits, loaded at BIG_FONT_ADDRESS
+var bigFontSet = []uint8{
+	0x3C, 0x7E, 0xE7, 0xC3, 0xC3, 0xC3, 0xC3, 0xE7, 0x7E, 0x3C, // 0
+	0x18, 0x38, 0x58, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x3C, // 1
+	0x3E, 0x7F, 0xC3, 0x06, 0x0C, 0x18, 0x30, 0x60, 0xFF, 0xFF, // 2
+	0x3C, 0x7E, 0xC3, 0x03, 0x0E, 0x0E, 0x03, 0xC3, 0x7E, 0x3C, // 3
+	0x06, 0x0E, 0x1E, 0x36, 0x66, 0xC6, 0xFF, 0xFF, 0x06, 0x06, // 4
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFC, 0xFE, 0x03, 0xC3, 0x7E, 0x3C, // 5
+	0x3E, 0x7C, 0xC0, 0xC0, 0xFC, 0xFE, 0xC3, 0xC3, 0x7E, 0x3C, // 6
+	0xFF, 0xFF, 0x03, 0x06, 0x0C, 0x18, 0x30, 0x60, 0x60, 0x60, // 7
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7E, 0x7E, 0xC3, 0xC3, 0x7E, 0x3C, // 8
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7F, 0x3F, 0x03, 0x03, 0x3E, 0x7C, // 9
+	0x7E, 0xFF, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xC3, // A
+	0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, // B
+	0x3C, 0xFF, 0xC3, 0xC0, 0xC0, 0xC0, 0xC0, 0xC3, 0xFF, 0x3C, // C
+	0xFC, 0xFE, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFE, 0xFC, // D
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, // E
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xC0, 0xC0, // F
+}
+
 type Emulator struct {
 	ProgramCounter uint16
 	Ram            [RAM_SIZE]byte
-	Screen         [SCREEN_TOTAL]uint8
+	Screen         []uint8
+	ScreenWidth    uint16
+	ScreenHeight   uint16
 	VRegisters     [REGISTER_COUNT]uint8
 	IRegister      uint16
 	Stack          [STACK_SIZE]uint16
@@ -46,11 +86,106 @@ type Emulator struct {
 	SoundTimer     uint16
 	Opcode         uint16
 	Keys           [16]uint8
+	Quirks         Quirks
+	Machine        Machine
+	RPLFlags       [RPL_FLAG_COUNT]uint8
+
+	// XO-CHIP state. Planes is a bitmask of the bitplanes that drawing,
+	// clearing and scrolling apply to, bit 0 for plane 1 and bit 1 for
+	// plane 2. Each Screen pixel holds one bit per plane.
+	Planes       uint8
+	AudioPattern [AUDIO_PATTERN_SIZE]uint8
+	Pitch        uint8
+
+	// Random feeds Cxnn. NewEmulator seeds it from the clock, set it with
+	// NewRand for runs that can be repeated.
+	Random Rand
+
+	// Exited is set by the SUPER-CHIP 00FD instruction
+	Exited bool
+
+	// WaitingForFrame is set by Dxyn under the DisplayWait quirk. The
+	// Runner ends the frame early when it sees it.
+	WaitingForFrame bool
+
+	// Fx0A waits for a key to go down and then up. HeldKey is the key it
+	// saw go down, while KeyHeld is set.
+	HeldKey uint8
+	KeyHeld bool
+
+	// RomHash is the SHA-1 of the last ROM passed to LoadRom
+	RomHash [20]byte
+
+	// MemoryPolicy decides what happens to fetches and accesses through I
+	// past the end of memory
+	MemoryPolicy MemoryPolicy
+
+	// OnMemoryAccess, when set, is called for every read or write that an
+	// instruction makes through I. Instruction fetches aren't included.
+	OnMemoryAccess func(address uint16, write bool)
+
+	// Tracer, when set, is given a TraceEntry after every Tick
+	Tracer Tracer
+
+	// The first out of bounds access by the current instruction
+	fault error
+
+	// The error that stopped the ROM
+	err error
 }
 
-func (e *Emulator) Tick() {
+// Tick runs one instruction. Errors are prefixed with the address of the
+// instruction that failed.
+func (e *Emulator) Tick() error {
+	if e.err != nil {
+		return e.err
+	}
+
+	if e.Exited {
+		return nil
+	}
+
+	pc := e.ProgramCounter
+	e.WaitingForFrame = false
+
+	if e.Tracer != nil {
+		defer e.trace(pc, DecodeInstruction(e.Ram[:], pc), e.VRegisters)
+	}
+
 	opcode := e.Fetch()
-	e.Decode(opcode)
+
+	var err error
+	if e.fault == nil {
+		err = e.Decode(opcode)
+	}
+
+	// Go back to the instruction that went out of bounds, so the debugger
+	// shows it. Anything it changed before then stays changed.
+	if e.fault != nil {
+		err = e.fault
+		e.fault = nil
+		e.ProgramCounter = pc
+	}
+
+	if err != nil {
+		e.err = fmt.Errorf("0x%03X: %w", pc, err)
+		return e.err
+	}
+
+	return nil
+}
+
+func (e *Emulator) trace(pc uint16, instruction Instruction, previous [REGISTER_COUNT]uint8) {
+	e.Tracer.Trace(TraceEntry{
+		Address:      pc,
+		Instruction:  instruction,
+		Previous:     previous,
+		VRegisters:   e.VRegisters,
+		IRegister:    e.IRegister,
+		StackPointer: e.StackPointer,
+		DelayTimer:   e.DelayTimer,
+		SoundTimer:   e.SoundTimer,
+	})
 }
 
 func (e *Emulator) TickTimers() {
@@ -64,49 +199,104 @@ func (e *Emulator) TickTimers() {
 	}
 }
 
-func (e *Emulator) LoadRom(filepath string) {
+// LoadRom copies the ROM at filepath into memory at START_ADDRESS. It
+// returns ErrRomTooLarge if the ROM doesn't fit in the machine's memory,
+// so set Machine first.
+func (e *Emulator) LoadRom(filepath string) error {
 	data, err := os.ReadFile(filepath)
 
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	if space := e.MemorySize() - uint32(START_ADDRESS); uint32(len(data)) > space {
+		return fmt.Errorf("%w: %d bytes, %s has room for %d", ErrRomTooLarge, len(data), e.Machine, space)
 	}
 
 	for i, v := range data {
 		e.Ram[uint16(i)+START_ADDRESS] = v
 	}
+
+	e.RomHash = sha1.Sum(data)
+
+	return nil
 }
 
-func (e *Emulator) Push(value uint16) {
+func (e *Emulator) Push(value uint16) error {
+	if e.StackPointer >= uint16(STACK_SIZE) {
+		return ErrStackOverflow
+	}
+
 	e.Stack[e.StackPointer] = value
 	e.StackPointer += 1
+
+	return nil
 }
 
-func (e *Emulator) Pop() uint16 {
+func (e *Emulator) Pop() (uint16, error) {
+	if e.StackPointer == 0 {
+		return 0, ErrStackUnderflow
+	}
+
 	e.StackPointer -= 1
-	return e.Stack[e.StackPointer]
+	return e.Stack[e.StackPointer], nil
 }
 
 func (e *Emulator) Key(value uint8, pressed uint8) {
 	e.Keys[value] = pressed
 }
 
+// fetchOpcode reads the opcode at the program counter without moving it.
+func (e *Emulator) fetchOpcode() uint16 {
+	return uint16(e.Ram[e.ProgramCounter])<<8 | uint16(e.Ram[e.ProgramCounter+1])
+}
+
+// Fetch reads the opcode at the program counter and moves past it. Past the
+// end of memory the program counter wraps to 0, or with MemoryHalt and
+// MemoryTrap it stays where it is and Tick fails. The program counter is
+// 16 bits, so on XO-CHIP it always wraps.
 func (e *Emulator) Fetch() uint16 {
-	first_code := e.Ram[e.ProgramCounter]
-	second_code := e.Ram[e.ProgramCounter+1]
-	e.Opcode = (uint16(first_code) << 8) | uint16(second_code)
+	pc := uint32(e.ProgramCounter)
 
-	if e.ProgramCounter < 4094 {
+	if pc+2 < e.MemorySize() {
+		e.Opcode = uint16(e.Ram[pc])<<8 | uint16(e.Ram[pc+1])
 		e.ProgramCounter += 2
+		return e.Opcode
+	}
+
+	first_address, ok := e.memoryAddress(uint32(e.ProgramCounter))
+	second_address, ok_second := e.memoryAddress(uint32(e.ProgramCounter) + 1)
+	if !ok || !ok_second {
+		return 0
+	}
+
+	e.Opcode = uint16(e.Ram[first_address])<<8 | uint16(e.Ram[second_address])
+
+	if e.MemoryPolicy == MemoryWrap {
+		e.ProgramCounter, _ = e.memoryAddress(uint32(e.ProgramCounter) + 2)
 	} else {
-		e.ProgramCounter = START_ADDRESS
+		e.ProgramCounter += 2
 	}
 
 	return e.Opcode
 }
 
-func (e *Emulator) Decode(opcode uint16) {
-	decoder := Decoder{emu: e}
-	decoder.Run(opcode)
+// SUPER-CHIP has 8 RPL flags, XO-CHIP has 16.
+func (e *Emulator) rplFlagCount() int {
+	if e.Machine >= MachineXOChip {
+		return int(RPL_FLAG_COUNT)
+	}
+
+	return 8
+}
+
+// MemorySize is how much of Ram the current machine can address.
+func (e *Emulator) MemorySize() uint32 {
+	if e.Machine >= MachineXOChip {
+		return RAM_SIZE
+	}
+
+	return CLASSIC_RAM_SIZE
 }
 
 func NewEmulator() Emulator {
@@ -114,10 +304,18 @@ func NewEmulator() Emulator {
 	emu.ProgramCounter = START_ADDRESS
 	emu.DelayTimer = 0
 	emu.SoundTimer = 0
+	emu.Planes = 1
+	emu.Pitch = DEFAULT_PITCH
+	emu.Random = NewRand(uint64(time.Now().UnixNano()))
+	emu.SetResolution(false)
 
 	for i, v := range fontSet {
 		emu.Ram[i] = v
 	}
 
+	for i, v := range bigFontSet {
+		emu.Ram[BIG_FONT_ADDRESS+uint16(i)] = v
+	}
+
 	return emu
 }
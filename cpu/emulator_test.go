@@ -0,0 +1,88 @@
+package cpu
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeRom(t *testing.T, size int) string {
+	path := filepath.Join(t.TempDir(), "rom.ch8")
+	assert.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+
+	return path
+}
+
+func TestLoadRom(t *testing.T) {
+	t.Run("Loads a ROM that fills memory", func(t *testing.T) {
+		emu := NewEmulator()
+		assert.NoError(t, emu.LoadRom(writeRom(t, 3584)))
+	})
+
+	t.Run("Rejects a ROM bigger than memory", func(t *testing.T) {
+		emu := NewEmulator()
+		assert.ErrorIs(t, emu.LoadRom(writeRom(t, 3585)), ErrRomTooLarge)
+	})
+
+	t.Run("Loads a bigger ROM on XO-CHIP", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Machine = MachineXOChip
+		assert.NoError(t, emu.LoadRom(writeRom(t, 3585)))
+	})
+
+	t.Run("Returns read errors", func(t *testing.T) {
+		emu := NewEmulator()
+		assert.ErrorIs(t, emu.LoadRom(filepath.Join(t.TempDir(), "missing.ch8")), os.ErrNotExist)
+	})
+}
+
+func TestStack(t *testing.T) {
+	emu := NewEmulator()
+
+	_, err := emu.Pop()
+	assert.ErrorIs(t, err, ErrStackUnderflow)
+
+	for i := 0; i < int(STACK_SIZE); i++ {
+		assert.NoError(t, emu.Push(uint16(i)))
+	}
+	assert.ErrorIs(t, emu.Push(0x300), ErrStackOverflow)
+
+	value, err := emu.Pop()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(STACK_SIZE-1), value)
+}
+
+func TestTickErrors(t *testing.T) {
+	t.Run("Invalid opcode", func(t *testing.T) {
+		emu := NewEmulator()
+		copy(emu.Ram[START_ADDRESS:], []uint8{0x00, 0xE0, 0xE0, 0x00})
+
+		assert.NoError(t, emu.Tick())
+
+		err := emu.Tick()
+		assert.ErrorIs(t, err, ErrInvalidOpcode)
+		assert.EqualError(t, err, "0x202: invalid opcode E000")
+	})
+
+	t.Run("Return with an empty stack", func(t *testing.T) {
+		emu := NewEmulator()
+		copy(emu.Ram[START_ADDRESS:], []uint8{0x00, 0xEE})
+
+		assert.ErrorIs(t, emu.Tick(), ErrStackUnderflow)
+	})
+
+	t.Run("Recursion overflows the stack", func(t *testing.T) {
+		emu := NewEmulator()
+		// 0x200: CALL 0x200
+		copy(emu.Ram[START_ADDRESS:], []uint8{0x22, 0x00})
+
+		var err error
+		for i := 0; i <= int(STACK_SIZE) && err == nil; i++ {
+			err = emu.Tick()
+		}
+
+		assert.ErrorIs(t, err, ErrStackOverflow)
+	})
+}
@@ -0,0 +1,266 @@
+package cpu
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	TICKS_PER_FRAME int           = 10
+	FRAME_DELAY     time.Duration = time.Second / 60
+)
+
+// Frontend is the host side of the emulator. It shows the screen, feeds
+// key presses back into the emulator and plays the buzzer.
+type Frontend interface {
+	// Present draws a finished frame. The screen is width x height pixels,
+	// row by row, and is only valid until Present returns.
+	Present(screen []uint8, width uint16, height uint16)
+
+	// PollInput passes pending key events to the emulator. It returns false
+	// once the user has asked to quit.
+	PollInput(emulator *Emulator) bool
+
+	// PlayTone turns the buzzer on or off.
+	PlayTone(on bool)
+}
+
+// Runner owns the frame loop shared by every Frontend. Each frame it runs
+// a frame's worth of instructions and ticks the timers, then presents the
+// screen and polls for input. Frames are paced by wall time, one every
+// FrameDelay, and when the host falls behind several frames run before
+// the next Present to catch up.
+type Runner struct {
+	Frontend Frontend
+
+	// FrameDelay is how long a frame lasts. 0 runs frames back to back as
+	// fast as possible.
+	FrameDelay time.Duration
+
+	Timing                Timing
+	InstructionsPerSecond int
+
+	// Paused stops frames from running, FastForward runs them
+	// FAST_FORWARD_SPEED times faster. Frontends set these from hotkeys.
+	Paused      bool
+	FastForward bool
+
+	// Frames stops the loop after this many frames when set.
+	Frames int
+
+	// StopOnHalt stops the loop once the emulator has Halted.
+	StopOnHalt bool
+
+	// Record, when set, has every key press and release added to it.
+	Record *Movie
+
+	// Replay, when set, presses and releases keys as the movie says and
+	// ignores the keys from the Frontend until the movie ends.
+	Replay *Movie
+
+	// Capture, when set, is given the screen after every frame.
+	Capture *Capture
+
+	// Debug, when set, is handed the emulator as soon as an access out of
+	// bounds stops it under MemoryTrap. If the ROM has been resumed by the
+	// time Debug returns, Run carries on with it, otherwise Run returns
+	// the error.
+	Debug func(emulator *Emulator)
+
+	// Instructions and VIP microseconds left over from the last frame
+	carry   int
+	vipTime int
+
+	// Wall time not yet used up by frames
+	last time.Time
+	lag  time.Duration
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func NewRunner(frontend Frontend) Runner {
+	return Runner{
+		Frontend:              frontend,
+		FrameDelay:            FRAME_DELAY,
+		InstructionsPerSecond: DEFAULT_IPS,
+		now:                   time.Now,
+		sleep:                 time.Sleep,
+	}
+}
+
+// Run runs frames until the user quits, the ROM stops or Frames is
+// reached. It returns the first error from Tick, which also stops it.
+func (r *Runner) Run(emulator *Emulator) error {
+	tone := false
+	var err error
+	next_event := 0
+	var replay_keys [16]uint8
+	frame := 0
+	stopped := false
+
+	r.last = r.now()
+	r.lag = r.FrameDelay
+
+	for !stopped {
+		for due := r.framesDue(); due > 0; due-- {
+			if r.Frames > 0 && frame >= r.Frames {
+				stopped = true
+				break
+			}
+
+			// Keys change between frames, so apply the ones from after
+			// the last frame
+			if r.Replay != nil && frame <= r.Replay.Frames {
+				emulator.Keys = replay_keys
+				next_event = r.Replay.replay(frame-1, next_event, emulator)
+				replay_keys = emulator.Keys
+			}
+
+			err = r.runFrame(emulator)
+			if r.Debug != nil && emulator.MemoryPolicy == MemoryTrap && errors.Is(err, ErrMemoryOutOfBounds) {
+				r.Debug(emulator)
+				err = emulator.Err()
+
+				// Don't rush to catch up with the time spent debugging
+				r.last = r.now()
+			}
+
+			if r.Capture != nil {
+				r.Capture.add(frame, emulator.Screen, emulator.ScreenWidth, emulator.ScreenHeight)
+			}
+			frame++
+
+			if err != nil || emulator.Exited || (r.StopOnHalt && emulator.Halted()) {
+				stopped = true
+				break
+			}
+		}
+
+		if (emulator.SoundTimer > 0) != tone {
+			tone = !tone
+			r.Frontend.PlayTone(tone)
+		}
+
+		r.Frontend.Present(emulator.Screen, emulator.ScreenWidth, emulator.ScreenHeight)
+
+		keys := emulator.Keys
+
+		if !r.Frontend.PollInput(emulator) {
+			break
+		}
+
+		if r.Record != nil {
+			r.Record.record(frame-1, keys, emulator.Keys)
+		}
+
+		if r.Frames > 0 && frame >= r.Frames {
+			break
+		}
+
+		if !stopped {
+			r.wait()
+		}
+	}
+
+	if tone {
+		r.Frontend.PlayTone(false)
+	}
+
+	return err
+}
+
+// runFrame runs one frame of instructions, then ticks the timers.
+func (r *Runner) runFrame(emulator *Emulator) error {
+	instructions := 0
+
+	if r.Timing == TimingVIP {
+		r.vipTime += VIP_FRAME_TIME
+	} else {
+		r.carry += r.InstructionsPerSecond
+		instructions = r.carry / 60
+		r.carry %= 60
+	}
+
+	for i := 0; r.Timing == TimingVIP && r.vipTime > 0 || i < instructions; i++ {
+		if r.Timing == TimingVIP {
+			r.vipTime -= vipInstructionTime(emulator.fetchOpcode())
+		}
+
+		if err := emulator.Tick(); err != nil {
+			return err
+		}
+
+		if emulator.Halted() || emulator.WaitingForFrame {
+			r.vipTime = 0
+			break
+		}
+	}
+
+	emulator.TickTimers()
+
+	return nil
+}
+
+// framesDue says how many frames to run before the next Present.
+func (r *Runner) framesDue() int {
+	if r.Paused {
+		return 0
+	}
+
+	if r.FrameDelay <= 0 {
+		return 1
+	}
+
+	now := r.now()
+	elapsed := now.Sub(r.last)
+	r.last = now
+
+	if r.FastForward {
+		elapsed *= time.Duration(FAST_FORWARD_SPEED)
+	}
+
+	r.lag += elapsed
+	due := int(r.lag / r.FrameDelay)
+
+	if due > MAX_CATCH_UP_FRAMES {
+		due = MAX_CATCH_UP_FRAMES
+		r.lag = 0
+	} else {
+		r.lag -= time.Duration(due) * r.FrameDelay
+	}
+
+	return due
+}
+
+// wait sleeps until the next frame is due.
+func (r *Runner) wait() {
+	if r.Paused {
+		r.sleep(FRAME_DELAY)
+		r.last = r.now()
+		return
+	}
+
+	if r.FrameDelay <= 0 {
+		return
+	}
+
+	remaining := r.FrameDelay - r.lag
+	if r.FastForward {
+		remaining /= time.Duration(FAST_FORWARD_SPEED)
+	}
+
+	if remaining > 0 {
+		r.sleep(remaining)
+	}
+}
+
+// SpeedUp and SpeedDown change InstructionsPerSecond by a quarter. They
+// have no effect with TimingVIP.
+func (r *Runner) SpeedUp() {
+	r.InstructionsPerSecond = min(r.InstructionsPerSecond*5/4, MAX_IPS)
+}
+
+func (r *Runner) SpeedDown() {
+	r.InstructionsPerSecond = max(r.InstructionsPerSecond*4/5, MIN_IPS)
+}
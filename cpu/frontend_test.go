@@ -0,0 +1,171 @@
+package cpu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeFrontend struct {
+	frames int
+	tones  []bool
+	quitAt int
+}
+
+func (f *fakeFrontend) Present(screen []uint8, width uint16, height uint16) {
+	f.frames++
+}
+
+func (f *fakeFrontend) PollInput(emulator *Emulator) bool {
+	return f.quitAt == 0 || f.frames < f.quitAt
+}
+
+func (f *fakeFrontend) PlayTone(on bool) {
+	f.tones = append(f.tones, on)
+}
+
+func TestRunnerRun(t *testing.T) {
+	t.Run("Stops when the frontend quits", func(t *testing.T) {
+		emu := NewEmulator()
+		// 0x200: JP 0x200
+		copy(emu.Ram[START_ADDRESS:], []uint8{0x12, 0x00})
+
+		frontend := fakeFrontend{quitAt: 5}
+		runner := NewRunner(&frontend)
+		runner.FrameDelay = 0
+		runner.Run(&emu)
+
+		assert.Equal(t, 5, frontend.frames)
+		assert.True(t, emu.Halted())
+	})
+
+	t.Run("Plays a tone while the sound timer is running", func(t *testing.T) {
+		emu := NewEmulator()
+		// 0x200: LD V0, 2, 0x202: LD ST, V0, 0x204: JP 0x204
+		copy(emu.Ram[START_ADDRESS:], []uint8{0x60, 0x02, 0xF0, 0x18, 0x12, 0x04})
+
+		frontend := fakeFrontend{}
+		runner := NewRunner(&frontend)
+		runner.FrameDelay = 0
+		runner.Frames = 4
+		runner.Run(&emu)
+
+		assert.Equal(t, []bool{true, false}, frontend.tones)
+	})
+}
+
+func TestRunnerRunError(t *testing.T) {
+	emu := NewEmulator()
+	// 0x200: CLS, 0x202: an invalid opcode
+	copy(emu.Ram[START_ADDRESS:], []uint8{0x00, 0xE0, 0xFF, 0xFF})
+
+	frontend := fakeFrontend{}
+	runner := NewRunner(&frontend)
+	runner.FrameDelay = 0
+
+	assert.ErrorIs(t, runner.Run(&emu), ErrInvalidOpcode)
+	assert.Equal(t, 1, frontend.frames)
+}
+
+func TestRunnerInstructionsPerSecond(t *testing.T) {
+	run := func(runner *Runner, frames int) int {
+		emu := NewEmulator()
+		// 0x200: ADD V0, 1, 0x202: JP 0x200
+		copy(emu.Ram[START_ADDRESS:], []uint8{0x70, 0x01, 0x12, 0x00})
+
+		tracer := recordingTracer{}
+		emu.Tracer = &tracer
+
+		runner.FrameDelay = 0
+		runner.Frames = frames
+		assert.NoError(t, runner.Run(&emu))
+
+		return len(tracer.entries)
+	}
+
+	t.Run("Runs a 60th of the instructions each frame", func(t *testing.T) {
+		runner := NewRunner(&fakeFrontend{})
+		runner.InstructionsPerSecond = 120
+		assert.Equal(t, 6, run(&runner, 3))
+	})
+
+	t.Run("Carries fractions over to the next frame", func(t *testing.T) {
+		runner := NewRunner(&fakeFrontend{})
+		runner.InstructionsPerSecond = 90
+		assert.Equal(t, 3, run(&runner, 2))
+	})
+
+	t.Run("VIP timing fills a frame with instruction times", func(t *testing.T) {
+		runner := NewRunner(&fakeFrontend{})
+		runner.Timing = TimingVIP
+
+		want := 0
+		for time := VIP_FRAME_TIME; time > 0; want++ {
+			time -= vipInstructionTime([]uint16{0x7001, 0x1200}[want%2])
+		}
+
+		assert.Equal(t, want, run(&runner, 1))
+	})
+}
+
+func TestRunnerFramesDue(t *testing.T) {
+	clock := time.Unix(0, 0)
+	advance := func(frames float64) {
+		clock = clock.Add(time.Duration(frames * float64(FRAME_DELAY)))
+	}
+
+	runner := NewRunner(&fakeFrontend{})
+	runner.now = func() time.Time { return clock }
+	runner.last = clock
+	runner.lag = runner.FrameDelay
+
+	assert.Equal(t, 1, runner.framesDue())
+
+	advance(2.5)
+	assert.Equal(t, 2, runner.framesDue())
+
+	advance(0.6)
+	assert.Equal(t, 1, runner.framesDue())
+
+	runner.FastForward = true
+	advance(1)
+	assert.Equal(t, FAST_FORWARD_SPEED, runner.framesDue())
+	runner.FastForward = false
+
+	advance(3600 * 60)
+	assert.Equal(t, MAX_CATCH_UP_FRAMES, runner.framesDue())
+
+	runner.Paused = true
+	advance(1)
+	assert.Equal(t, 0, runner.framesDue())
+}
+
+func TestRunnerWait(t *testing.T) {
+	var slept time.Duration
+
+	runner := NewRunner(&fakeFrontend{})
+	runner.sleep = func(d time.Duration) { slept = d }
+	runner.lag = FRAME_DELAY / 4
+
+	runner.wait()
+	assert.Equal(t, FRAME_DELAY-FRAME_DELAY/4, slept)
+
+	runner.FastForward = true
+	runner.wait()
+	assert.Equal(t, (FRAME_DELAY-FRAME_DELAY/4)/time.Duration(FAST_FORWARD_SPEED), slept)
+}
+
+func TestRunnerSpeed(t *testing.T) {
+	runner := NewRunner(&fakeFrontend{})
+
+	runner.SpeedUp()
+	assert.Equal(t, 750, runner.InstructionsPerSecond)
+
+	runner.SpeedDown()
+	assert.Equal(t, 600, runner.InstructionsPerSecond)
+
+	runner.InstructionsPerSecond = MIN_IPS
+	runner.SpeedDown()
+	assert.Equal(t, MIN_IPS, runner.InstructionsPerSecond)
+}
@@ -0,0 +1,67 @@
+package cpu
+
+// HaltReason says why a ROM has stopped running.
+type HaltReason uint8
+
+const (
+	NotHalted HaltReason = iota
+
+	// HaltSelfJump is a 1nnn that jumps to its own address, which is how
+	// most ROMs and test suites end
+	HaltSelfJump
+
+	// HaltExit is the SUPER-CHIP 00FD instruction
+	HaltExit
+
+	// HaltError is an instruction that failed, see Err
+	HaltError
+)
+
+func (r HaltReason) String() string {
+	switch r {
+	case HaltSelfJump:
+		return "jumped to itself"
+	case HaltExit:
+		return "exited"
+	case HaltError:
+		return "crashed"
+	}
+
+	return "running"
+}
+
+// Halted says whether the ROM has stopped. Once it has, Tick changes
+// nothing, and after a crash it returns the same error again.
+func (e *Emulator) Halted() bool {
+	return e.HaltReason() != NotHalted
+}
+
+// HaltReason says why the ROM stopped, or NotHalted while it runs.
+func (e *Emulator) HaltReason() HaltReason {
+	switch {
+	case e.err != nil:
+		return HaltError
+	case e.Exited:
+		return HaltExit
+	case isSelfJump(e.fetchOpcode(), e.ProgramCounter):
+		return HaltSelfJump
+	}
+
+	return NotHalted
+}
+
+// Err is the error that stopped the ROM, if any.
+func (e *Emulator) Err() error {
+	return e.err
+}
+
+// Resume clears the error that stopped the ROM, so the next Tick tries the
+// instruction that failed again.
+func (e *Emulator) Resume() {
+	e.err = nil
+}
+
+// A 1nnn that jumps to its own address is how most ROMs stop.
+func isSelfJump(opcode uint16, address uint16) bool {
+	return opcode&0xF000 == 0x1000 && opcode&0x0FFF == address
+}
@@ -0,0 +1,80 @@
+package cpu
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHaltReason(t *testing.T) {
+	t.Run("Running", func(t *testing.T) {
+		emu := NewEmulator()
+		copy(emu.Ram[START_ADDRESS:], []uint8{0x00, 0xE0})
+
+		assert.False(t, emu.Halted())
+		assert.Equal(t, NotHalted, emu.HaltReason())
+	})
+
+	t.Run("Jump to itself", func(t *testing.T) {
+		emu := NewEmulator()
+		// 0x200: CLS, 0x202: JP 0x202
+		copy(emu.Ram[START_ADDRESS:], []uint8{0x00, 0xE0, 0x12, 0x02})
+
+		assert.NoError(t, emu.Tick())
+		assert.True(t, emu.Halted())
+		assert.Equal(t, HaltSelfJump, emu.HaltReason())
+		assert.Equal(t, "jumped to itself", emu.HaltReason().String())
+	})
+
+	t.Run("00FD", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Machine = MachineSuperChip
+		copy(emu.Ram[START_ADDRESS:], []uint8{0x00, 0xFD, 0x00, 0xE0})
+
+		assert.NoError(t, emu.Tick())
+		assert.Equal(t, HaltExit, emu.HaltReason())
+
+		assert.NoError(t, emu.Tick())
+		assert.Equal(t, uint16(0x202), emu.ProgramCounter)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		emu := NewEmulator()
+		copy(emu.Ram[START_ADDRESS:], []uint8{0xFF, 0xFF})
+
+		err := emu.Tick()
+		assert.ErrorIs(t, err, ErrInvalidOpcode)
+		assert.Equal(t, HaltError, emu.HaltReason())
+		assert.Equal(t, err, emu.Err())
+
+		// The ROM stays where it crashed
+		assert.Equal(t, err, emu.Tick())
+		assert.Equal(t, uint16(0x202), emu.ProgramCounter)
+	})
+
+	t.Run("Loading a state recovers from an error", func(t *testing.T) {
+		emu := NewEmulator()
+		copy(emu.Ram[START_ADDRESS:], []uint8{0x00, 0xE0, 0xFF, 0xFF})
+
+		var state bytes.Buffer
+		assert.NoError(t, emu.SaveState(&state))
+
+		assert.NoError(t, emu.Tick())
+		assert.Error(t, emu.Tick())
+
+		assert.NoError(t, emu.LoadState(&state))
+		assert.False(t, emu.Halted())
+		assert.NoError(t, emu.Tick())
+	})
+}
+
+func TestHeadlessLeavesEmulatorHalted(t *testing.T) {
+	emu := NewEmulator()
+	// 0x200: JP 0x200
+	copy(emu.Ram[START_ADDRESS:], []uint8{0x12, 0x00})
+
+	headless := Headless{Output: &bytes.Buffer{}}
+	assert.NoError(t, headless.Run(&emu))
+	assert.Equal(t, HaltSelfJump, emu.HaltReason())
+}
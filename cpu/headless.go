@@ -0,0 +1,104 @@
+package cpu
+
+import (
+	"image/color"
+	"io"
+	"os"
+)
+
+// One character per pixel value, the last two only show up on XO-CHIP
+var pixelChars = [4]byte{'.', '#', '+', '@'}
+
+// Headless runs the emulator without opening a window. It drives the
+// emulator at the same cadence as Display and writes the final screen to
+// Output once it stops.
+type Headless struct {
+	Frames int
+	Output io.Writer
+
+	// Timing, InstructionsPerSecond, Record, Replay, Capture and Debug are
+	// passed on to the Runner, 0 instructions per second means DEFAULT_IPS
+	Timing                Timing
+	InstructionsPerSecond int
+	Record                *Movie
+	Replay                *Movie
+	Capture               *Capture
+	Debug                 func(emulator *Emulator)
+
+	// Screenshot, when set, gets the last frame as a PNG, ScreenshotScale
+	// image pixels to a CHIP-8 pixel, in Colors
+	Screenshot      io.Writer
+	ScreenshotScale int
+	Colors          []color.RGBA
+
+	screen []uint8
+	width  uint16
+	height uint16
+}
+
+// Run runs the emulator and writes the last frame to Output, and to
+// Screenshot, even when the ROM stopped with an error. The emulator is left
+// as it stopped, so Halted says whether Frames ran out first.
+func (h *Headless) Run(emulator *Emulator) error {
+	runner := NewRunner(h)
+	runner.FrameDelay = 0
+	runner.Frames = h.Frames
+	runner.StopOnHalt = true
+	runner.Timing = h.Timing
+	if h.InstructionsPerSecond > 0 {
+		runner.InstructionsPerSecond = h.InstructionsPerSecond
+	}
+	runner.Record = h.Record
+	runner.Replay = h.Replay
+	runner.Capture = h.Capture
+	runner.Debug = h.Debug
+	err := runner.Run(emulator)
+
+	if drawErr := h.DrawScreen(h.screen, h.width, h.height); err == nil {
+		err = drawErr
+	}
+
+	if h.Screenshot != nil {
+		shotErr := WriteScreenshot(h.Screenshot, h.screen, h.width, h.height, h.ScreenshotScale, h.Colors)
+		if err == nil {
+			err = shotErr
+		}
+	}
+
+	return err
+}
+
+func (h *Headless) Present(screen []uint8, width uint16, height uint16) {
+	h.screen = append(h.screen[:0], screen...)
+	h.width = width
+	h.height = height
+}
+
+func (h *Headless) PollInput(emulator *Emulator) bool {
+	return true
+}
+
+func (h *Headless) PlayTone(on bool) {
+}
+
+func (h *Headless) DrawScreen(screen []uint8, width uint16, height uint16) error {
+	out := h.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	line := make([]byte, width+1)
+	line[width] = '\n'
+
+	for y := uint16(0); y < height; y++ {
+		for x := uint16(0); x < width; x++ {
+			line[x] = pixelChars[screen[y*width+x]&0x3]
+		}
+
+		if _, err := out.Write(line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package cpu
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeadlessRun(t *testing.T) {
+	t.Run("Stops on a jump to itself", func(t *testing.T) {
+		emu := NewEmulator()
+		// 0x200: CLS, 0x202: LD F, V0, 0x204: DRW V0, V0, 5, 0x206: JP 0x206
+		copy(emu.Ram[START_ADDRESS:], []uint8{0x00, 0xE0, 0xF0, 0x29, 0xD0, 0x05, 0x12, 0x06})
+
+		var out bytes.Buffer
+		headless := Headless{Output: &out}
+		headless.Run(&emu)
+
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+
+		assert.Equal(t, int(SCREEN_HEIGHT), len(lines))
+		assert.Equal(t, "####....", lines[0][:8])
+		assert.Equal(t, "#..#....", lines[1][:8])
+	})
+
+	t.Run("Stops after the given number of frames", func(t *testing.T) {
+		emu := NewEmulator()
+		// 0x200: ADD V0, 1, 0x202: JP 0x200
+		copy(emu.Ram[START_ADDRESS:], []uint8{0x70, 0x01, 0x12, 0x00})
+
+		var out bytes.Buffer
+		headless := Headless{Frames: 3, Output: &out}
+		headless.Run(&emu)
+
+		assert.Equal(t, int(SCREEN_TOTAL+SCREEN_HEIGHT), out.Len())
+	})
+}
@@ -0,0 +1,271 @@
+package cpu
+
+// Op identifies an instruction independently of its operands.
+type Op uint8
+
+const (
+	OpInvalid Op = iota
+	OpSys
+	OpClear
+	OpReturn
+	OpScrollDown
+	OpScrollUp
+	OpScrollRight
+	OpScrollLeft
+	OpExit
+	OpLowRes
+	OpHighRes
+	OpJump
+	OpCall
+	OpSkipEqualByte
+	OpSkipNotEqualByte
+	OpSkipEqual
+	OpSaveRange
+	OpLoadRange
+	OpLoadByte
+	OpAddByte
+	OpLoad
+	OpOr
+	OpAnd
+	OpXor
+	OpAdd
+	OpSub
+	OpShiftRight
+	OpSubN
+	OpShiftLeft
+	OpSkipNotEqual
+	OpLoadI
+	OpJumpV0
+	OpRandom
+	OpDraw
+	OpSkipKey
+	OpSkipNotKey
+	OpLoadLongI
+	OpPlane
+	OpAudio
+	OpGetDelay
+	OpWaitKey
+	OpSetDelay
+	OpSetSound
+	OpAddI
+	OpFont
+	OpBigFont
+	OpBCD
+	OpPitch
+	OpSaveRegisters
+	OpLoadRegisters
+	OpSaveFlags
+	OpLoadFlags
+)
+
+// Instruction is an opcode split into its operation and operands. Not
+// every operand is used by every Op. Long is the second word of the
+// XO-CHIP F000 NNNN.
+type Instruction struct {
+	Op     Op
+	Opcode uint16
+	X      uint8
+	Y      uint8
+	N      uint8
+	NN     uint8
+	NNN    uint16
+	Long   uint16
+}
+
+// Machine is the first machine that has the instruction.
+func (op Op) Machine() Machine {
+	switch op {
+	case OpScrollDown, OpScrollRight, OpScrollLeft, OpExit, OpLowRes, OpHighRes, OpBigFont, OpSaveFlags, OpLoadFlags:
+		return MachineSuperChip
+
+	case OpScrollUp, OpSaveRange, OpLoadRange, OpLoadLongI, OpPlane, OpAudio, OpPitch:
+		return MachineXOChip
+	}
+
+	return MachineChip8
+}
+
+// Size is the number of bytes the instruction takes up in memory.
+func (i Instruction) Size() uint16 {
+	if i.Op == OpLoadLongI {
+		return 4
+	}
+
+	return 2
+}
+
+// DecodeInstruction decodes the instruction at address. It knows every
+// instruction from CHIP-8, SUPER-CHIP and XO-CHIP, whichever machine they
+// belong to. Anything past the end of ram reads as 0.
+func DecodeInstruction(ram []byte, address uint16) Instruction {
+	opcode := wordAt(ram, address)
+
+	i := Instruction{
+		Op:     OpInvalid,
+		Opcode: opcode,
+		X:      uint8((opcode & 0x0F00) >> 8),
+		Y:      uint8((opcode & 0x00F0) >> 4),
+		N:      uint8(opcode & 0x000F),
+		NN:     uint8(opcode & 0x00FF),
+		NNN:    opcode & 0x0FFF,
+	}
+
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch {
+		case opcode == 0x00E0:
+			i.Op = OpClear
+		case opcode == 0x00EE:
+			i.Op = OpReturn
+		case opcode&0xFFF0 == 0x00C0:
+			i.Op = OpScrollDown
+		case opcode&0xFFF0 == 0x00D0:
+			i.Op = OpScrollUp
+		case opcode == 0x00FB:
+			i.Op = OpScrollRight
+		case opcode == 0x00FC:
+			i.Op = OpScrollLeft
+		case opcode == 0x00FD:
+			i.Op = OpExit
+		case opcode == 0x00FE:
+			i.Op = OpLowRes
+		case opcode == 0x00FF:
+			i.Op = OpHighRes
+		default:
+			i.Op = OpSys
+		}
+
+	case 0x1000:
+		i.Op = OpJump
+
+	case 0x2000:
+		i.Op = OpCall
+
+	case 0x3000:
+		i.Op = OpSkipEqualByte
+
+	case 0x4000:
+		i.Op = OpSkipNotEqualByte
+
+	case 0x5000:
+		switch i.N {
+		case 0:
+			i.Op = OpSkipEqual
+		case 2:
+			i.Op = OpSaveRange
+		case 3:
+			i.Op = OpLoadRange
+		}
+
+	case 0x6000:
+		i.Op = OpLoadByte
+
+	case 0x7000:
+		i.Op = OpAddByte
+
+	case 0x8000:
+		switch i.N {
+		case 0x0:
+			i.Op = OpLoad
+		case 0x1:
+			i.Op = OpOr
+		case 0x2:
+			i.Op = OpAnd
+		case 0x3:
+			i.Op = OpXor
+		case 0x4:
+			i.Op = OpAdd
+		case 0x5:
+			i.Op = OpSub
+		case 0x6:
+			i.Op = OpShiftRight
+		case 0x7:
+			i.Op = OpSubN
+		case 0xE:
+			i.Op = OpShiftLeft
+		}
+
+	case 0x9000:
+		if i.N == 0 {
+			i.Op = OpSkipNotEqual
+		}
+
+	case 0xA000:
+		i.Op = OpLoadI
+
+	case 0xB000:
+		i.Op = OpJumpV0
+
+	case 0xC000:
+		i.Op = OpRandom
+
+	case 0xD000:
+		i.Op = OpDraw
+
+	case 0xE000:
+		switch i.NN {
+		case 0x9E:
+			i.Op = OpSkipKey
+		case 0xA1:
+			i.Op = OpSkipNotKey
+		}
+
+	case 0xF000:
+		switch i.NN {
+		case 0x00:
+			if i.X == 0 {
+				i.Op = OpLoadLongI
+				i.Long = wordAt(ram, address+2)
+			}
+		case 0x01:
+			i.Op = OpPlane
+		case 0x02:
+			if i.X == 0 {
+				i.Op = OpAudio
+			}
+		case 0x07:
+			i.Op = OpGetDelay
+		case 0x0A:
+			i.Op = OpWaitKey
+		case 0x15:
+			i.Op = OpSetDelay
+		case 0x18:
+			i.Op = OpSetSound
+		case 0x1E:
+			i.Op = OpAddI
+		case 0x29:
+			i.Op = OpFont
+		case 0x30:
+			i.Op = OpBigFont
+		case 0x33:
+			i.Op = OpBCD
+		case 0x3A:
+			i.Op = OpPitch
+		case 0x55:
+			i.Op = OpSaveRegisters
+		case 0x65:
+			i.Op = OpLoadRegisters
+		case 0x75:
+			i.Op = OpSaveFlags
+		case 0x85:
+			i.Op = OpLoadFlags
+		}
+	}
+
+	return i
+}
+
+// wordAt reads a big endian word, treating anything past the end of ram
+// as 0.
+func wordAt(ram []byte, address uint16) uint16 {
+	var word uint16
+
+	for i := 0; i < 2; i++ {
+		word <<= 8
+		if int(address)+i < len(ram) {
+			word |= uint16(ram[int(address)+i])
+		}
+	}
+
+	return word
+}
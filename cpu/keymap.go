@@ -0,0 +1,220 @@
+package cpu
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Keymap maps host keys and game controller buttons to the 16 CHIP-8
+// keys. Keys are SDL key names such as "Q", "Space" or "Up" and Buttons
+// are SDL controller button names such as "a" or "dpup". Several host
+// keys can press the same CHIP-8 key.
+type Keymap struct {
+	Keys    map[string]uint8
+	Buttons map[string]uint8
+}
+
+// The CHIP-8 keypad
+//
+//	1 2 3 C
+//	4 5 6 D
+//	7 8 9 E
+//	A 0 B F
+var keypad = []uint8{
+	0x1, 0x2, 0x3, 0xC,
+	0x4, 0x5, 0x6, 0xD,
+	0x7, 0x8, 0x9, 0xE,
+	0xA, 0x0, 0xB, 0xF,
+}
+
+// Each preset lists the keys in the same place as the keypad on that
+// layout, row by row
+var keymapPresets = map[string][]string{
+	"qwerty": {"1", "2", "3", "4", "Q", "W", "E", "R", "A", "S", "D", "F", "Z", "X", "C", "V"},
+	"azerty": {"1", "2", "3", "4", "A", "Z", "E", "R", "Q", "S", "D", "F", "W", "X", "C", "V"},
+	"dvorak": {"1", "2", "3", "4", "'", ",", ".", "P", "A", "O", "E", "U", ";", "Q", "J", "K"},
+}
+
+// Most games move with 5, 8, 7 and 9 and act with 6 or 4
+var defaultButtons = map[string]uint8{
+	"dpup":    0x5,
+	"dpdown":  0x8,
+	"dpleft":  0x7,
+	"dpright": 0x9,
+	"a":       0x6,
+	"b":       0x4,
+}
+
+// Host keys and controller buttons for the key hints in a ROM database
+var hintBindings = map[string][]string{
+	"up":    {"Up", "pad:dpup"},
+	"down":  {"Down", "pad:dpdown"},
+	"left":  {"Left", "pad:dpleft"},
+	"right": {"Right", "pad:dpright"},
+	"a":     {"Space", "pad:a"},
+	"b":     {"Left Shift", "pad:b"},
+}
+
+// KeymapByName returns a preset keymap with the default controller
+// buttons.
+func KeymapByName(name string) (Keymap, error) {
+	keys, ok := keymapPresets[name]
+	if !ok {
+		return Keymap{}, fmt.Errorf("unknown keymap %q (choose from %s)", name, strings.Join(KeymapNames(), ", "))
+	}
+
+	keymap := Keymap{Keys: map[string]uint8{}, Buttons: map[string]uint8{}}
+
+	for i, key := range keys {
+		keymap.Keys[key] = keypad[i]
+	}
+
+	for button, key := range defaultButtons {
+		keymap.Buttons[button] = key
+	}
+
+	return keymap, nil
+}
+
+func KeymapNames() []string {
+	names := make([]string, 0, len(keymapPresets))
+	for name := range keymapPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Bind parses a binding such as "5=Up,W", which makes the Up and W keys
+// press CHIP-8 key 5. Names starting with "pad:" are controller buttons,
+// as in "5=pad:dpup". Host keys bound to another CHIP-8 key are moved.
+func (k Keymap) Bind(binding string) error {
+	key_text, hosts, found := strings.Cut(binding, "=")
+	if !found || hosts == "" {
+		return fmt.Errorf("invalid key binding %q, use KEY=HOSTKEY,HOSTKEY", binding)
+	}
+
+	key, err := parseChip8Key(key_text)
+	if err != nil {
+		return err
+	}
+
+	for _, host := range strings.Split(hosts, ",") {
+		k.bind(key, strings.TrimSpace(host))
+	}
+
+	return nil
+}
+
+// BindHints binds the arrow keys, Space and Left Shift, and the matching
+// controller buttons, to the CHIP-8 keys a ROM uses for "up", "down",
+// "left", "right", "a" and "b". Other hints are ignored.
+func (k Keymap) BindHints(hints map[string]uint8) {
+	for hint, key := range hints {
+		if key > 0xF {
+			continue
+		}
+
+		for _, host := range hintBindings[hint] {
+			k.bind(key, host)
+		}
+	}
+}
+
+func (k Keymap) bind(key uint8, host string) {
+	if button, ok := strings.CutPrefix(host, "pad:"); ok {
+		k.Buttons[button] = key
+	} else {
+		k.Keys[host] = key
+	}
+}
+
+func parseChip8Key(text string) (uint8, error) {
+	key, err := strconv.ParseUint(strings.TrimSpace(text), 16, 4)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CHIP-8 key %q, use a hex digit from 0 to F", text)
+	}
+
+	return uint8(key), nil
+}
+
+// KeymapConfig is a keymap file. It starts from a preset, then binds the
+// host keys listed for each CHIP-8 key, then does the same for the entry
+// in Roms matching the ROM being run, by SHA-1 or by file name:
+//
+//	{
+//	  "preset": "azerty",
+//	  "keys": {"5": ["Up"], "8": ["Down"], "6": ["Space", "pad:a"]},
+//	  "roms": {
+//	    "pong.rom": {"1": ["W"], "4": ["S"], "C": ["Up"], "D": ["Down"]}
+//	  }
+//	}
+type KeymapConfig struct {
+	Preset string                         `json:"preset"`
+	Keys   map[string][]string            `json:"keys"`
+	Roms   map[string]map[string][]string `json:"roms"`
+}
+
+func LoadKeymapConfig(r io.Reader) (KeymapConfig, error) {
+	var config KeymapConfig
+	if err := json.NewDecoder(r).Decode(&config); err != nil {
+		return KeymapConfig{}, fmt.Errorf("not a keymap file: %w", err)
+	}
+
+	return config, nil
+}
+
+// Keymap builds the keymap for a ROM. hints are the ROM's key hints from
+// a ROM database, which are bound straight after the preset so that the
+// rest of the file can change them.
+func (c KeymapConfig) Keymap(rom_name string, rom_hash [20]byte, hints map[string]uint8) (Keymap, error) {
+	preset := c.Preset
+	if preset == "" {
+		preset = "qwerty"
+	}
+
+	keymap, err := KeymapByName(preset)
+	if err != nil {
+		return Keymap{}, err
+	}
+
+	keymap.BindHints(hints)
+
+	if err := keymap.bindAll(c.Keys); err != nil {
+		return Keymap{}, err
+	}
+
+	override, ok := c.Roms[hex.EncodeToString(rom_hash[:])]
+	if !ok {
+		override, ok = c.Roms[rom_name]
+	}
+
+	if ok {
+		if err := keymap.bindAll(override); err != nil {
+			return Keymap{}, fmt.Errorf("%s: %w", rom_name, err)
+		}
+	}
+
+	return keymap, nil
+}
+
+func (k Keymap) bindAll(bindings map[string][]string) error {
+	for key_text, hosts := range bindings {
+		key, err := parseChip8Key(key_text)
+		if err != nil {
+			return err
+		}
+
+		for _, host := range hosts {
+			k.bind(key, host)
+		}
+	}
+
+	return nil
+}
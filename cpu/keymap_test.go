@@ -0,0 +1,116 @@
+package cpu
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeymapByName(t *testing.T) {
+	t.Run("Maps the keypad by position", func(t *testing.T) {
+		qwerty, err := KeymapByName("qwerty")
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(0x4), qwerty.Keys["Q"])
+		assert.Equal(t, uint8(0xA), qwerty.Keys["Z"])
+
+		azerty, err := KeymapByName("azerty")
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(0x4), azerty.Keys["A"])
+		assert.Equal(t, uint8(0xA), azerty.Keys["W"])
+
+		dvorak, err := KeymapByName("dvorak")
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(0x5), dvorak.Keys[","])
+		assert.Len(t, dvorak.Keys, 16)
+	})
+
+	t.Run("Includes controller buttons", func(t *testing.T) {
+		keymap, _ := KeymapByName("qwerty")
+		assert.Equal(t, uint8(0x5), keymap.Buttons["dpup"])
+	})
+
+	t.Run("Rejects unknown presets", func(t *testing.T) {
+		_, err := KeymapByName("colemak")
+		assert.Error(t, err)
+	})
+}
+
+func TestKeymapBind(t *testing.T) {
+	keymap, _ := KeymapByName("qwerty")
+
+	assert.NoError(t, keymap.Bind("5=Up, Space,pad:x"))
+	assert.Equal(t, uint8(0x5), keymap.Keys["Up"])
+	assert.Equal(t, uint8(0x5), keymap.Keys["Space"])
+	assert.Equal(t, uint8(0x5), keymap.Buttons["x"])
+	assert.Equal(t, uint8(0x5), keymap.Keys["W"])
+
+	// Moves W from 5 to F
+	assert.NoError(t, keymap.Bind("f=W"))
+	assert.Equal(t, uint8(0xF), keymap.Keys["W"])
+
+	assert.Error(t, keymap.Bind("5"))
+	assert.Error(t, keymap.Bind("G=Up"))
+}
+
+func TestKeymapBindHints(t *testing.T) {
+	keymap, _ := KeymapByName("qwerty")
+	keymap.BindHints(map[string]uint8{"up": 1, "down": 4, "a": 0xF, "player2Up": 0xC})
+
+	assert.Equal(t, uint8(0x1), keymap.Keys["Up"])
+	assert.Equal(t, uint8(0x1), keymap.Buttons["dpup"])
+	assert.Equal(t, uint8(0x4), keymap.Keys["Down"])
+	assert.Equal(t, uint8(0xF), keymap.Keys["Space"])
+	assert.Equal(t, uint8(0xF), keymap.Buttons["a"])
+	assert.Len(t, keymap.Keys, 19)
+}
+
+func TestKeymapConfig(t *testing.T) {
+	config, err := LoadKeymapConfig(strings.NewReader(`{
+		"preset": "azerty",
+		"keys": {"6": ["Space", "pad:y"]},
+		"roms": {
+			"pong.rom": {"C": ["Up"], "D": ["Down"]},
+			"0100000000000000000000000000000000000000": {"1": ["Up"]}
+		}
+	}`))
+	assert.NoError(t, err)
+
+	t.Run("Starts from the preset", func(t *testing.T) {
+		keymap, err := config.Keymap("other.ch8", [20]byte{}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(0x4), keymap.Keys["A"])
+		assert.Equal(t, uint8(0x6), keymap.Keys["Space"])
+		assert.Equal(t, uint8(0x6), keymap.Buttons["y"])
+		assert.NotContains(t, keymap.Keys, "Up")
+	})
+
+	t.Run("Applies the ROM override by name", func(t *testing.T) {
+		keymap, err := config.Keymap("pong.rom", [20]byte{}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(0xC), keymap.Keys["Up"])
+		assert.Equal(t, uint8(0xD), keymap.Keys["Down"])
+	})
+
+	t.Run("Applies the ROM override by hash", func(t *testing.T) {
+		keymap, err := config.Keymap("pong.rom", [20]byte{1}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(0x1), keymap.Keys["Up"])
+	})
+
+	t.Run("Lets the file change key hints", func(t *testing.T) {
+		keymap, err := config.Keymap("pong.rom", [20]byte{}, map[string]uint8{"up": 1, "a": 2})
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(0xC), keymap.Keys["Up"])
+		assert.Equal(t, uint8(0x6), keymap.Keys["Space"])
+		assert.Equal(t, uint8(0x2), keymap.Buttons["a"])
+	})
+
+	t.Run("Rejects bad CHIP-8 keys", func(t *testing.T) {
+		config, err := LoadKeymapConfig(strings.NewReader(`{"keys": {"10": ["Up"]}}`))
+		assert.NoError(t, err)
+
+		_, err = config.Keymap("pong.rom", [20]byte{}, nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,52 @@
+package cpu
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Machine is the CHIP-8 variant being emulated. Instructions added by later
+// variants are rejected as invalid on earlier ones.
+type Machine uint8
+
+const (
+	MachineChip8 Machine = iota
+	MachineSuperChip
+	MachineXOChip
+)
+
+var machineNames = map[string]Machine{
+	"chip8":  MachineChip8,
+	"schip":  MachineSuperChip,
+	"xochip": MachineXOChip,
+}
+
+func (m Machine) String() string {
+	for name, machine := range machineNames {
+		if machine == m {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("Machine(%d)", uint8(m))
+}
+
+func MachineByName(name string) (Machine, error) {
+	machine, ok := machineNames[name]
+	if !ok {
+		return MachineChip8, fmt.Errorf("unknown machine %q (choose from %s)", name, strings.Join(MachineNames(), ", "))
+	}
+
+	return machine, nil
+}
+
+func MachineNames() []string {
+	names := make([]string, 0, len(machineNames))
+	for name := range machineNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,101 @@
+package cpu
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrMemoryOutOfBounds = errors.New("memory access out of bounds")
+
+// MemoryPolicy decides what happens when the program counter or an access
+// through I goes past the end of the machine's memory, which is 4K before
+// XO-CHIP.
+type MemoryPolicy uint8
+
+const (
+	// MemoryWrap wraps addresses around to the start of memory
+	MemoryWrap MemoryPolicy = iota
+
+	// MemoryHalt stops the ROM with an error wrapping
+	// ErrMemoryOutOfBounds, at the instruction that made the access
+	MemoryHalt
+
+	// MemoryTrap stops the ROM as MemoryHalt does, then hands it to the
+	// debugger straight away when the frontend has one
+	MemoryTrap
+)
+
+var memoryPolicyNames = map[string]MemoryPolicy{
+	"wrap": MemoryWrap,
+	"halt": MemoryHalt,
+	"trap": MemoryTrap,
+}
+
+func MemoryPolicyByName(name string) (MemoryPolicy, error) {
+	policy, ok := memoryPolicyNames[name]
+	if !ok {
+		names := make([]string, 0, len(memoryPolicyNames))
+		for name := range memoryPolicyNames {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return MemoryWrap, fmt.Errorf("unknown memory policy %q (choose from %s)", name, strings.Join(names, ", "))
+	}
+
+	return policy, nil
+}
+
+// memoryAddress applies the memory policy to address. It returns false
+// when the access must not happen, having recorded the fault for Tick.
+func (e *Emulator) memoryAddress(address uint32) (uint16, bool) {
+	if address < e.MemorySize() {
+		return uint16(address), true
+	}
+
+	return e.outOfBounds(address)
+}
+
+func (e *Emulator) outOfBounds(address uint32) (uint16, bool) {
+	size := e.MemorySize()
+
+	if e.MemoryPolicy == MemoryWrap {
+		return uint16(address % size), true
+	}
+
+	if e.fault == nil {
+		e.fault = fmt.Errorf("%w: 0x%X, %s has %d bytes", ErrMemoryOutOfBounds, address, e.Machine, size)
+	}
+
+	return 0, false
+}
+
+// readI reads the byte offset bytes past I.
+func (e *Emulator) readI(offset int) uint8 {
+	address, ok := e.memoryAddress(uint32(e.IRegister) + uint32(offset))
+	if !ok {
+		return 0
+	}
+
+	if e.OnMemoryAccess != nil {
+		e.OnMemoryAccess(address, false)
+	}
+
+	return e.Ram[address]
+}
+
+// writeI writes the byte offset bytes past I.
+func (e *Emulator) writeI(offset int, value uint8) {
+	address, ok := e.memoryAddress(uint32(e.IRegister) + uint32(offset))
+	if !ok {
+		return
+	}
+
+	if e.OnMemoryAccess != nil {
+		e.OnMemoryAccess(address, true)
+	}
+
+	e.Ram[address] = value
+}
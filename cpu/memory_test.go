@@ -0,0 +1,175 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryPolicyByName(t *testing.T) {
+	policy, err := MemoryPolicyByName("trap")
+	assert.NoError(t, err)
+	assert.Equal(t, MemoryTrap, policy)
+
+	_, err = MemoryPolicyByName("ignore")
+	assert.EqualError(t, err, `unknown memory policy "ignore" (choose from halt, trap, wrap)`)
+}
+
+func TestFetchPastEndOfMemory(t *testing.T) {
+	t.Run("Wraps to the start of memory", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.ProgramCounter = 0xFFE
+		emu.Ram[0xFFE] = 0x00
+		emu.Ram[0xFFF] = 0xE0
+
+		assert.NoError(t, emu.Tick())
+		assert.Equal(t, uint16(0), emu.ProgramCounter)
+	})
+
+	t.Run("Wraps the second byte of an odd address", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.ProgramCounter = 0xFFF
+		emu.Ram[0xFFF] = 0x60
+		emu.Ram[0] = 0x42
+
+		assert.NoError(t, emu.Tick())
+		assert.Equal(t, uint8(0x42), emu.VRegisters[0])
+		assert.Equal(t, uint16(1), emu.ProgramCounter)
+	})
+
+	t.Run("Halts at the instruction", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.MemoryPolicy = MemoryHalt
+		emu.ProgramCounter = 0xFFF
+
+		err := emu.Tick()
+		assert.ErrorIs(t, err, ErrMemoryOutOfBounds)
+		assert.EqualError(t, err, "0xFFF: memory access out of bounds: 0x1000, chip8 has 4096 bytes")
+		assert.Equal(t, uint16(0xFFF), emu.ProgramCounter)
+	})
+
+	t.Run("Halts after running off the end", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.MemoryPolicy = MemoryHalt
+		emu.ProgramCounter = 0xFFE
+		emu.Ram[0xFFE] = 0x00
+		emu.Ram[0xFFF] = 0xE0
+
+		assert.NoError(t, emu.Tick())
+		assert.ErrorIs(t, emu.Tick(), ErrMemoryOutOfBounds)
+		assert.Equal(t, uint16(0x1000), emu.ProgramCounter)
+	})
+
+	t.Run("Uses all of XO-CHIP memory", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Machine = MachineXOChip
+		emu.MemoryPolicy = MemoryHalt
+		emu.ProgramCounter = 0xFFFE
+		emu.Ram[0xFFFE] = 0x00
+		emu.Ram[0xFFFF] = 0xE0
+
+		assert.NoError(t, emu.Tick())
+		assert.Equal(t, uint16(0), emu.ProgramCounter)
+	})
+}
+
+func TestAccessThroughIPastEndOfMemory(t *testing.T) {
+	tests := []struct {
+		name   string
+		opcode uint16
+		i      uint16
+	}{
+		{"Dxyn", 0xD012, 0xFFF},
+		{"Fx33", 0xF033, 0xFFE},
+		{"Fx55", 0xF155, 0xFFF},
+		{"Fx65", 0xF165, 0xFFF},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name+" wraps", func(t *testing.T) {
+			emu := NewEmulator()
+			emu.IRegister = test.i
+			emu.VRegisters[0] = 255
+			emu.VRegisters[1] = 7
+			copy(emu.Ram[START_ADDRESS:], []uint8{uint8(test.opcode >> 8), uint8(test.opcode)})
+
+			accessed := []uint16{}
+			emu.OnMemoryAccess = func(address uint16, write bool) {
+				accessed = append(accessed, address)
+			}
+
+			assert.NoError(t, emu.Tick())
+			assert.Contains(t, accessed, uint16(0))
+			assert.NotContains(t, accessed, uint16(0x1000))
+		})
+
+		t.Run(test.name+" halts", func(t *testing.T) {
+			emu := NewEmulator()
+			emu.MemoryPolicy = MemoryHalt
+			emu.IRegister = test.i
+			copy(emu.Ram[START_ADDRESS:], []uint8{uint8(test.opcode >> 8), uint8(test.opcode)})
+
+			assert.ErrorIs(t, emu.Tick(), ErrMemoryOutOfBounds)
+			assert.Equal(t, START_ADDRESS, emu.ProgramCounter)
+			assert.Equal(t, fontSet[0], emu.Ram[0])
+		})
+	}
+
+	t.Run("Halts past 64K on XO-CHIP", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Machine = MachineXOChip
+		emu.MemoryPolicy = MemoryHalt
+		emu.IRegister = 0xFFFF
+		// 0x200: LD [I], V1
+		copy(emu.Ram[START_ADDRESS:], []uint8{0xF1, 0x55})
+
+		assert.ErrorIs(t, emu.Tick(), ErrMemoryOutOfBounds)
+	})
+}
+
+func TestRunnerMemoryTrap(t *testing.T) {
+	emu := NewEmulator()
+	emu.MemoryPolicy = MemoryTrap
+	emu.IRegister = 0xFFF
+	// 0x200: CLS, 0x202: LD [I], V1
+	copy(emu.Ram[START_ADDRESS:], []uint8{0x00, 0xE0, 0xF1, 0x55})
+
+	var trapped uint16
+	runner := NewRunner(&fakeFrontend{})
+	runner.FrameDelay = 0
+	runner.Debug = func(emulator *Emulator) {
+		trapped = emulator.ProgramCounter
+	}
+
+	assert.ErrorIs(t, runner.Run(&emu), ErrMemoryOutOfBounds)
+	assert.Equal(t, uint16(0x202), trapped)
+
+	t.Run("Carries on once the debugger resumes", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.MemoryPolicy = MemoryTrap
+		emu.IRegister = 0xFFF
+		emu.VRegisters[1] = 7
+		// 0x200: LD [I], V1, 0x202: JP 0x202
+		copy(emu.Ram[START_ADDRESS:], []uint8{0xF1, 0x55, 0x12, 0x02})
+
+		runner := NewRunner(&fakeFrontend{})
+		runner.FrameDelay = 0
+		runner.StopOnHalt = true
+		runner.Debug = func(emulator *Emulator) {
+			emulator.IRegister = 0x300
+			emulator.Resume()
+		}
+
+		assert.NoError(t, runner.Run(&emu))
+		assert.Equal(t, uint8(7), emu.Ram[0x301])
+		assert.Equal(t, HaltSelfJump, emu.HaltReason())
+	})
+
+	t.Run("Only traps with MemoryTrap", func(t *testing.T) {
+		emu.MemoryPolicy = MemoryHalt
+		trapped = 0
+
+		assert.ErrorIs(t, runner.Run(&emu), ErrMemoryOutOfBounds)
+		assert.Equal(t, uint16(0), trapped)
+	})
+}
@@ -0,0 +1,132 @@
+package cpu
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrMovieRom = errors.New("movie was recorded with a different ROM")
+
+// Movie is a recording of the keys pressed while a ROM ran, along with
+// everything else needed to run it again the same way: the ROM, machine,
+// quirks, random seed and speed. It's saved as JSON.
+type Movie struct {
+	RomHash string       `json:"rom_sha1"`
+	Machine string       `json:"machine"`
+	Quirks  Quirks       `json:"quirks"`
+	Seed    uint64       `json:"seed"`
+	Timing  string       `json:"timing,omitempty"`
+	IPS     int          `json:"ips,omitempty"`
+	Frames  int          `json:"frames"`
+	Events  []MovieEvent `json:"events"`
+}
+
+// MovieEvent is a key going down or up. It takes effect after the frame
+// numbered Frame, counting from 0, has run.
+type MovieEvent struct {
+	Frame   int   `json:"frame"`
+	Key     uint8 `json:"key"`
+	Pressed bool  `json:"pressed"`
+}
+
+// NewMovie starts a recording of emulator, which should have its ROM
+// loaded and not have run yet.
+func NewMovie(emulator *Emulator) Movie {
+	return Movie{
+		RomHash: hex.EncodeToString(emulator.RomHash[:]),
+		Machine: emulator.Machine.String(),
+		Quirks:  emulator.Quirks,
+		Seed:    emulator.Random.State,
+		Events:  []MovieEvent{},
+	}
+}
+
+func LoadMovie(r io.Reader) (Movie, error) {
+	var movie Movie
+	if err := json.NewDecoder(r).Decode(&movie); err != nil {
+		return Movie{}, fmt.Errorf("not a movie file: %w", err)
+	}
+
+	if _, err := MachineByName(movie.Machine); err != nil {
+		return Movie{}, err
+	}
+
+	if movie.Timing != "" {
+		if _, err := TimingByName(movie.Timing); err != nil {
+			return Movie{}, err
+		}
+	}
+
+	if movie.IPS != 0 && (movie.IPS < MIN_IPS || movie.IPS > MAX_IPS) {
+		return Movie{}, fmt.Errorf("movie runs at %d instructions per second, it must be from %d to %d", movie.IPS, MIN_IPS, MAX_IPS)
+	}
+
+	for _, event := range movie.Events {
+		if event.Key > 0xF {
+			return Movie{}, fmt.Errorf("movie presses key %d, there are only 16", event.Key)
+		}
+	}
+
+	return movie, nil
+}
+
+func (m *Movie) Save(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(m)
+}
+
+// Apply sets up emulator the way it was when the movie was recorded. The
+// ROM has to be loaded first so its hash can be checked.
+func (m *Movie) Apply(emulator *Emulator) error {
+	if hash := hex.EncodeToString(emulator.RomHash[:]); hash != m.RomHash {
+		return fmt.Errorf("%w: recorded with %s, running %s", ErrMovieRom, m.RomHash, hash)
+	}
+
+	machine, err := MachineByName(m.Machine)
+	if err != nil {
+		return err
+	}
+
+	emulator.Machine = machine
+	emulator.Quirks = m.Quirks
+	emulator.Random = NewRand(m.Seed)
+
+	return nil
+}
+
+// record adds an event for every key that differs between before and
+// after, which are the keys at the start and end of frame.
+func (m *Movie) record(frame int, before [16]uint8, after [16]uint8) {
+	for key := range after {
+		if before[key] != after[key] {
+			m.Events = append(m.Events, MovieEvent{
+				Frame:   frame,
+				Key:     uint8(key),
+				Pressed: after[key] == 1,
+			})
+		}
+	}
+
+	m.Frames = frame + 1
+}
+
+// replay presses and releases the keys for frame, starting from the
+// event at next. It returns the next event still to come.
+func (m *Movie) replay(frame int, next int, emulator *Emulator) int {
+	for ; next < len(m.Events) && m.Events[next].Frame <= frame; next++ {
+		event := m.Events[next]
+
+		if event.Pressed {
+			emulator.Key(event.Key, 1)
+		} else {
+			emulator.Key(event.Key, 0)
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,97 @@
+package cpu
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// scriptedFrontend holds down a key for one frame at the frames listed
+type scriptedFrontend struct {
+	fakeFrontend
+	presses map[int]uint8
+	frame   int
+}
+
+func (s *scriptedFrontend) PollInput(emulator *Emulator) bool {
+	emulator.Keys = [16]uint8{}
+	if key, ok := s.presses[s.frame]; ok {
+		emulator.Key(key, 1)
+	}
+	s.frame++
+
+	return true
+}
+
+// 0x200: LD V0, K, 0x202: RND V1, 0x1F, 0x204: LD F, V0,
+// 0x206: DRW V0, V1, 5, 0x208: JP 0x200
+var movieRom = []uint8{0xF0, 0x0A, 0xC1, 0x1F, 0xF0, 0x29, 0xD0, 0x15, 0x12, 0x00}
+
+func TestMovie(t *testing.T) {
+	emu := NewEmulator()
+	emu.Quirks = QuirksCosmacVIP
+	emu.RomHash = [20]byte{1}
+	copy(emu.Ram[START_ADDRESS:], movieRom)
+
+	movie := NewMovie(&emu)
+	runner := NewRunner(&scriptedFrontend{presses: map[int]uint8{3: 5, 8: 0xA, 9: 0xA}})
+	runner.FrameDelay = 0
+	runner.Frames = 20
+	runner.Record = &movie
+	assert.NoError(t, runner.Run(&emu))
+
+	assert.Equal(t, 20, movie.Frames)
+	assert.Equal(t, []MovieEvent{
+		{Frame: 3, Key: 5, Pressed: true},
+		{Frame: 4, Key: 5, Pressed: false},
+		{Frame: 8, Key: 0xA, Pressed: true},
+		{Frame: 10, Key: 0xA, Pressed: false},
+	}, movie.Events)
+
+	var file bytes.Buffer
+	assert.NoError(t, movie.Save(&file))
+	loaded, err := LoadMovie(&file)
+	assert.NoError(t, err)
+	assert.Equal(t, movie, loaded)
+
+	t.Run("Replays to the same screen", func(t *testing.T) {
+		replayed := NewEmulator()
+		replayed.RomHash = [20]byte{1}
+		copy(replayed.Ram[START_ADDRESS:], movieRom)
+		assert.NoError(t, loaded.Apply(&replayed))
+
+		// Keys from the frontend are ignored while the movie plays
+		runner := NewRunner(&scriptedFrontend{presses: map[int]uint8{1: 3}})
+		runner.FrameDelay = 0
+		runner.Frames = loaded.Frames
+		runner.Replay = &loaded
+		assert.NoError(t, runner.Run(&replayed))
+
+		assert.Equal(t, QuirksCosmacVIP, replayed.Quirks)
+		assert.Equal(t, emu.Screen, replayed.Screen)
+		assert.Equal(t, emu.VRegisters, replayed.VRegisters)
+		assert.Equal(t, emu.ProgramCounter, replayed.ProgramCounter)
+	})
+
+	t.Run("Rejects a different ROM", func(t *testing.T) {
+		other := NewEmulator()
+		other.RomHash = [20]byte{2}
+
+		assert.ErrorIs(t, loaded.Apply(&other), ErrMovieRom)
+	})
+}
+
+func TestLoadMovieErrors(t *testing.T) {
+	_, err := LoadMovie(bytes.NewReader([]byte("not json")))
+	assert.Error(t, err)
+
+	_, err = LoadMovie(bytes.NewReader([]byte(`{"machine": "nes"}`)))
+	assert.Error(t, err)
+
+	_, err = LoadMovie(bytes.NewReader([]byte(`{"machine": "chip8", "events": [{"frame": 1, "key": 16}]}`)))
+	assert.Error(t, err)
+
+	_, err = LoadMovie(bytes.NewReader([]byte(`{"machine": "chip8", "ips": -1}`)))
+	assert.Error(t, err)
+}
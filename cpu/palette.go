@@ -0,0 +1,145 @@
+package cpu
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Each theme lists the background color, then the colors of pixels in
+// plane 1, plane 2 and both planes. Only XO-CHIP ROMs use the last two.
+var themes = map[string][]color.RGBA{
+	"green": {
+		{0, 0, 0, 255},
+		{15, 255, 80, 255},
+		{0, 120, 40, 255},
+		{200, 255, 200, 255},
+	},
+	"amber": {
+		{20, 12, 0, 255},
+		{255, 176, 0, 255},
+		{140, 90, 0, 255},
+		{255, 224, 150, 255},
+	},
+	"lcd": {
+		{155, 188, 15, 255},
+		{15, 56, 15, 255},
+		{48, 98, 48, 255},
+		{139, 172, 15, 255},
+	},
+	"high-contrast": {
+		{0, 0, 0, 255},
+		{255, 255, 255, 255},
+		{255, 255, 0, 255},
+		{0, 255, 255, 255},
+	},
+}
+
+// The colors used when no others are given
+var defaultColors = themes["green"]
+
+func ThemeByName(name string) ([]color.RGBA, error) {
+	colors, ok := themes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q (choose from %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+
+	return colors, nil
+}
+
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// themeAfter returns the theme that follows the one colors are from in
+// ThemeNames, or the first theme when they aren't from one.
+func themeAfter(colors []color.RGBA) string {
+	if len(colors) == 0 {
+		colors = defaultColors
+	}
+
+	names := ThemeNames()
+	for i, name := range names {
+		if slices.Equal(themes[name], colors) {
+			return names[(i+1)%len(names)]
+		}
+	}
+
+	return names[0]
+}
+
+// ParseColor parses "#RRGGBB".
+func ParseColor(text string) (color.RGBA, error) {
+	hex_text, found := strings.CutPrefix(strings.TrimSpace(text), "#")
+	value, err := strconv.ParseUint(hex_text, 16, 32)
+
+	if !found || len(hex_text) != 6 || err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, use #RRGGBB", text)
+	}
+
+	return color.RGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 255}, nil
+}
+
+// ParseColors parses a comma separated list of up to four colors, such as
+// "#000000,#FFB000", background first.
+func ParseColors(text string) ([]color.RGBA, error) {
+	return parseColorList(strings.Split(text, ","))
+}
+
+// LoadColors reads a theme file, which is a JSON list of up to four
+// colors, background first:
+//
+//	["#000000", "#FFB000", "#8C5A00", "#FFE096"]
+func LoadColors(r io.Reader) ([]color.RGBA, error) {
+	var texts []string
+	if err := json.NewDecoder(r).Decode(&texts); err != nil {
+		return nil, fmt.Errorf("not a theme file: %w", err)
+	}
+
+	return parseColorList(texts)
+}
+
+func parseColorList(texts []string) ([]color.RGBA, error) {
+	if len(texts) > len(defaultColors) {
+		return nil, fmt.Errorf("%d colors given, a screen only has %d", len(texts), len(defaultColors))
+	}
+
+	colors := make([]color.RGBA, 0, len(texts))
+
+	for _, text := range texts {
+		c, err := ParseColor(text)
+		if err != nil {
+			return nil, err
+		}
+
+		colors = append(colors, c)
+	}
+
+	return colors, nil
+}
+
+// screenPalette fills in any colors missing from colors with the defaults.
+func screenPalette(colors []color.RGBA) color.Palette {
+	p := make(color.Palette, len(defaultColors))
+
+	for i := range p {
+		if i < len(colors) {
+			p[i] = colors[i]
+		} else {
+			p[i] = defaultColors[i]
+		}
+	}
+
+	return p
+}
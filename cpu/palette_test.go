@@ -0,0 +1,64 @@
+package cpu
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThemeByName(t *testing.T) {
+	for _, name := range ThemeNames() {
+		t.Run("Has four colors in "+name, func(t *testing.T) {
+			colors, err := ThemeByName(name)
+			assert.NoError(t, err)
+			assert.Len(t, colors, 4)
+		})
+	}
+
+	_, err := ThemeByName("sepia")
+	assert.Error(t, err)
+}
+
+func TestThemeAfter(t *testing.T) {
+	names := ThemeNames()
+
+	for i, name := range names {
+		t.Run("Follows "+name, func(t *testing.T) {
+			assert.Equal(t, names[(i+1)%len(names)], themeAfter(themes[name]))
+		})
+	}
+
+	t.Run("Follows green by default", func(t *testing.T) {
+		assert.Equal(t, themeAfter(themes["green"]), themeAfter(nil))
+	})
+
+	t.Run("Starts from the first theme for other colors", func(t *testing.T) {
+		assert.Equal(t, names[0], themeAfter([]color.RGBA{{1, 2, 3, 255}}))
+	})
+}
+
+func TestParseColors(t *testing.T) {
+	colors, err := ParseColors("#000000, #FFb000")
+	assert.NoError(t, err)
+	assert.Equal(t, []color.RGBA{{0, 0, 0, 255}, {255, 176, 0, 255}}, colors)
+
+	_, err = ParseColors("#000000,orange")
+	assert.Error(t, err)
+
+	_, err = ParseColors("#000,#FFFFFF")
+	assert.Error(t, err)
+
+	_, err = ParseColors("#000000,#000000,#000000,#000000,#000000")
+	assert.Error(t, err)
+}
+
+func TestLoadColors(t *testing.T) {
+	colors, err := LoadColors(strings.NewReader(`["#101010", "#FFFFFF"]`))
+	assert.NoError(t, err)
+	assert.Equal(t, []color.RGBA{{16, 16, 16, 255}, {255, 255, 255, 255}}, colors)
+
+	_, err = LoadColors(strings.NewReader(`{"colors": []}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package cpu
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Quirks selects between the behaviors that differ across CHIP-8
+// interpreters. The zero value matches this emulator's original behavior.
+type Quirks struct {
+	// VFReset makes 8xy1, 8xy2 and 8xy3 set VF to 0.
+	VFReset bool
+
+	// MemoryIncrement makes Fx55 and Fx65 leave I pointing past the last
+	// register, I += x + 1.
+	MemoryIncrement bool
+
+	// MemoryIncrementByX makes Fx55 and Fx65 add x to I, one short of
+	// MemoryIncrement. It takes precedence over MemoryIncrement.
+	MemoryIncrementByX bool
+
+	// ShiftVy makes 8xy6 and 8xyE shift Vy into Vx instead of shifting Vx
+	// in place.
+	ShiftVy bool
+
+	// Clipping makes Dxyn clip sprites at the screen edges. Only the
+	// starting position wraps.
+	Clipping bool
+
+	// JumpVx makes Bnnn jump to xnn + Vx instead of nnn + V0.
+	JumpVx bool
+
+	// DisplayWait makes Dxyn in low resolution wait for the next frame
+	// before the ROM carries on, like the COSMAC VIP waiting for vblank.
+	DisplayWait bool
+
+	// LoresTallSprites makes Dxy0 in low resolution draw an 8x16 sprite
+	// instead of a 16x16 one.
+	LoresTallSprites bool
+}
+
+var (
+	QuirksCosmacVIP = Quirks{
+		VFReset:         true,
+		MemoryIncrement: true,
+		ShiftVy:         true,
+		Clipping:        true,
+		DisplayWait:     true,
+	}
+
+	QuirksChip48 = Quirks{
+		MemoryIncrementByX: true,
+		Clipping:           true,
+		JumpVx:             true,
+	}
+
+	QuirksSuperChipModern = Quirks{
+		Clipping: true,
+		JumpVx:   true,
+	}
+
+	// SUPER-CHIP 1.0 and 1.1 on the HP48, which still added x to I and
+	// drew low resolution sprites at the pace of the screen
+	QuirksSuperChipLegacy = Quirks{
+		MemoryIncrementByX: true,
+		Clipping:           true,
+		JumpVx:             true,
+		DisplayWait:        true,
+		LoresTallSprites:   true,
+	}
+
+	QuirksXOChip = Quirks{
+		MemoryIncrement: true,
+		ShiftVy:         true,
+	}
+)
+
+// QuirkProfiles maps the names accepted on the command line to presets.
+var QuirkProfiles = map[string]Quirks{
+	"default":      {},
+	"vip":          QuirksCosmacVIP,
+	"chip48":       QuirksChip48,
+	"schip-modern": QuirksSuperChipModern,
+	"schip-legacy": QuirksSuperChipLegacy,
+	"xochip":       QuirksXOChip,
+}
+
+func QuirksByName(name string) (Quirks, error) {
+	quirks, ok := QuirkProfiles[name]
+	if !ok {
+		return Quirks{}, fmt.Errorf("unknown quirks profile %q (choose from %s)", name, strings.Join(QuirkProfileNames(), ", "))
+	}
+
+	return quirks, nil
+}
+
+func QuirkProfileNames() []string {
+	names := make([]string, 0, len(QuirkProfiles))
+	for name := range QuirkProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,28 @@
+package cpu
+
+// Rand is the random number generator behind Cxnn. Its whole state is
+// State, so copying a Rand or saving State in a save state replays the
+// same numbers. It's SplitMix64, which is fast and fine with any seed.
+type Rand struct {
+	State uint64
+}
+
+// NewRand returns a Rand that always produces the same numbers for the
+// same seed.
+func NewRand(seed uint64) Rand {
+	return Rand{State: seed}
+}
+
+func (r *Rand) Uint64() uint64 {
+	r.State += 0x9E3779B97F4A7C15
+
+	z := r.State
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+
+	return z ^ (z >> 31)
+}
+
+func (r *Rand) Byte() uint8 {
+	return uint8(r.Uint64() >> 56)
+}
@@ -0,0 +1,150 @@
+package cpu
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden screens in testdata")
+
+// romTest runs a ROM from the roms directory headless and compares the last
+// screen with testdata/<name>.txt.
+type romTest struct {
+	name    string
+	rom     string
+	machine Machine
+	quirks  Quirks
+	frames  int
+	input   []MovieEvent
+}
+
+// flags, quirks and keypad are built from the .asm files next to them and
+// draw their results, see the comments at the top of each
+var romTests = []romTest{
+	{name: "ibm-logo", rom: "ibm-logo.ch8", frames: 60},
+	{name: "test-opcode", rom: "test-opcode.ch8", frames: 120},
+	{
+		name:   "pong",
+		rom:    "pong.rom",
+		frames: 240,
+		input: []MovieEvent{
+			{Frame: 120, Key: 0x1, Pressed: true},
+			{Frame: 150, Key: 0x1, Pressed: false},
+			{Frame: 150, Key: 0xD, Pressed: true},
+			{Frame: 180, Key: 0xD, Pressed: false},
+		},
+	},
+	{name: "flags", rom: "flags.ch8", frames: 60},
+	{name: "quirks-default", rom: "quirks.ch8", frames: 60},
+	{name: "quirks-vip", rom: "quirks.ch8", quirks: QuirksCosmacVIP, frames: 60},
+	{
+		name:    "quirks-schip-legacy",
+		rom:     "quirks.ch8",
+		machine: MachineSuperChip,
+		quirks:  QuirksSuperChipLegacy,
+		frames:  60,
+	},
+	{
+		name:   "keypad",
+		rom:    "keypad.ch8",
+		frames: 60,
+		input: []MovieEvent{
+			{Frame: 10, Key: 0x5, Pressed: true},
+			{Frame: 20, Key: 0x5, Pressed: false},
+			{Frame: 30, Key: 0xA, Pressed: true},
+			{Frame: 40, Key: 0xA, Pressed: false},
+		},
+	},
+}
+
+func TestRoms(t *testing.T) {
+	for _, test := range romTests {
+		t.Run(test.name, func(t *testing.T) {
+			emu := NewEmulator()
+			emu.Machine = test.machine
+			emu.Quirks = test.quirks
+			emu.Random = NewRand(1)
+
+			if err := emu.LoadRom(filepath.Join("..", "roms", test.rom)); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			headless := Headless{Frames: test.frames, Output: &out}
+			if len(test.input) > 0 {
+				headless.Replay = &Movie{Frames: test.frames, Events: test.input}
+			}
+
+			if err := headless.Run(&emu); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", test.name+".txt")
+
+			if *update {
+				if err := os.WriteFile(golden, out.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v, run go test -run TestRoms -update to create it", err)
+			}
+
+			if !bytes.Equal(expected, out.Bytes()) {
+				t.Errorf("screen differs from %s\n%s", golden, diffScreens(string(expected), out.String()))
+			}
+		})
+	}
+}
+
+// diffScreens shows the expected and actual screens side by side, with the
+// rows that differ marked by a ! and the differing pixels marked with ^.
+func diffScreens(expected string, actual string) string {
+	expected_rows := strings.Split(strings.TrimSuffix(expected, "\n"), "\n")
+	actual_rows := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+
+	var diff strings.Builder
+	diff.WriteString("  expected | actual\n")
+
+	for i := 0; i < max(len(expected_rows), len(actual_rows)); i++ {
+		want, got := "", ""
+		if i < len(expected_rows) {
+			want = expected_rows[i]
+		}
+		if i < len(actual_rows) {
+			got = actual_rows[i]
+		}
+
+		if want == got {
+			diff.WriteString("  " + want + " | " + got + "\n")
+			continue
+		}
+
+		diff.WriteString("! " + want + " | " + got + "\n")
+
+		marks := []byte(strings.Repeat(" ", max(len(want), len(got))))
+		for x := range marks {
+			if x >= len(want) || x >= len(got) || want[x] != got[x] {
+				marks[x] = '^'
+			}
+		}
+		diff.WriteString("  " + strings.TrimRight(string(marks), " ") + "\n")
+	}
+
+	return diff.String()
+}
+
+func TestDiffScreens(t *testing.T) {
+	diff := diffScreens("..\n#.\n", "..\n.#\n")
+
+	assert.Equal(t, "  expected | actual\n  .. | ..\n! #. | .#\n  ^^\n", diff)
+}
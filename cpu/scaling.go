@@ -0,0 +1,60 @@
+package cpu
+
+import (
+	"fmt"
+)
+
+// Scaling decides how the screen fills a window that has been resized.
+type Scaling uint8
+
+const (
+	// ScalingAspect makes the screen as big as fits while keeping its
+	// shape, with borders on two sides
+	ScalingAspect Scaling = iota
+
+	// ScalingInteger makes every pixel a whole number of window pixels,
+	// so they are all the same size
+	ScalingInteger
+
+	// ScalingStretch fills the whole window
+	ScalingStretch
+)
+
+var scalingNames = map[string]Scaling{
+	"aspect":  ScalingAspect,
+	"integer": ScalingInteger,
+	"stretch": ScalingStretch,
+}
+
+func ScalingByName(name string) (Scaling, error) {
+	scaling, ok := scalingNames[name]
+	if !ok {
+		return ScalingAspect, fmt.Errorf("unknown scaling %q (choose from aspect, integer, stretch)", name)
+	}
+
+	return scaling, nil
+}
+
+// screenArea works out where a width x height screen goes in a window, as
+// the left and top of the screen and its size, all in window pixels. The
+// screen is centered.
+func screenArea(window_width int, window_height int, width int, height int, scaling Scaling) (int, int, int, int) {
+	area_width, area_height := window_width, window_height
+
+	switch scaling {
+	case ScalingAspect:
+		// Compare window_width/window_height with width/height without
+		// dividing
+		if window_width*height > window_height*width {
+			area_width = window_height * width / height
+		} else {
+			area_height = window_width * height / width
+		}
+
+	case ScalingInteger:
+		scale := max(min(window_width/width, window_height/height), 1)
+		area_width, area_height = width*scale, height*scale
+	}
+
+	return (window_width - area_width) / 2, (window_height - area_height) / 2, area_width, area_height
+}
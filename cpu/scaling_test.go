@@ -0,0 +1,48 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScreenArea(t *testing.T) {
+	area := func(window_width int, window_height int, width int, height int, scaling Scaling) []int {
+		left, top, area_width, area_height := screenArea(window_width, window_height, width, height, scaling)
+		return []int{left, top, area_width, area_height}
+	}
+
+	t.Run("Fills a window the same shape", func(t *testing.T) {
+		assert.Equal(t, []int{0, 0, 960, 480}, area(960, 480, 64, 32, ScalingAspect))
+		assert.Equal(t, []int{0, 0, 960, 480}, area(960, 480, 64, 32, ScalingInteger))
+	})
+
+	t.Run("Keeps the shape in a tall window", func(t *testing.T) {
+		assert.Equal(t, []int{0, 100, 640, 320}, area(640, 520, 64, 32, ScalingAspect))
+	})
+
+	t.Run("Keeps the shape in a wide window", func(t *testing.T) {
+		assert.Equal(t, []int{200, 0, 1000, 500}, area(1400, 500, 128, 64, ScalingAspect))
+	})
+
+	t.Run("Uses whole pixels", func(t *testing.T) {
+		assert.Equal(t, []int{20, 15, 960, 480}, area(1000, 510, 64, 32, ScalingInteger))
+	})
+
+	t.Run("Never shrinks pixels below one", func(t *testing.T) {
+		assert.Equal(t, []int{-32, -16, 128, 64}, area(64, 32, 128, 64, ScalingInteger))
+	})
+
+	t.Run("Stretches", func(t *testing.T) {
+		assert.Equal(t, []int{0, 0, 1000, 510}, area(1000, 510, 64, 32, ScalingStretch))
+	})
+}
+
+func TestScalingByName(t *testing.T) {
+	scaling, err := ScalingByName("integer")
+	assert.NoError(t, err)
+	assert.Equal(t, ScalingInteger, scaling)
+
+	_, err = ScalingByName("fit")
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package cpu
+
+// SetResolution switches between the 64x32 and the SUPER-CHIP 128x64
+// screen. The screen is cleared either way.
+func (e *Emulator) SetResolution(hires bool) {
+	if hires {
+		e.ScreenWidth = HIRES_SCREEN_WIDTH
+		e.ScreenHeight = HIRES_SCREEN_HEIGHT
+	} else {
+		e.ScreenWidth = SCREEN_WIDTH
+		e.ScreenHeight = SCREEN_HEIGHT
+	}
+
+	e.Screen = make([]uint8, int(e.ScreenWidth)*int(e.ScreenHeight))
+}
+
+func (e *Emulator) HiRes() bool {
+	return e.ScreenWidth == HIRES_SCREEN_WIDTH
+}
+
+// ClearScreen clears the selected planes.
+func (e *Emulator) ClearScreen() {
+	for i := range e.Screen {
+		e.Screen[i] &^= e.Planes
+	}
+}
+
+func (e *Emulator) ScrollDown(n uint16) {
+	e.scroll(0, int(n))
+}
+
+func (e *Emulator) ScrollUp(n uint16) {
+	e.scroll(0, -int(n))
+}
+
+func (e *Emulator) ScrollRight(n uint16) {
+	e.scroll(int(n), 0)
+}
+
+func (e *Emulator) ScrollLeft(n uint16) {
+	e.scroll(-int(n), 0)
+}
+
+// scroll moves the selected planes dx pixels right and dy pixels down.
+// Pixels scrolled in from outside the screen are blank.
+func (e *Emulator) scroll(dx int, dy int) {
+	width := int(e.ScreenWidth)
+	height := int(e.ScreenHeight)
+	moved := make([]uint8, len(e.Screen))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			from_x := x - dx
+			from_y := y - dy
+
+			if from_x >= 0 && from_x < width && from_y >= 0 && from_y < height {
+				moved[y*width+x] = e.Screen[from_y*width+from_x] & e.Planes
+			}
+		}
+	}
+
+	for i := range e.Screen {
+		e.Screen[i] = e.Screen[i]&^e.Planes | moved[i]
+	}
+}
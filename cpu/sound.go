@@ -0,0 +1,89 @@
+package cpu
+
+import (
+	"math"
+)
+
+const (
+	SAMPLE_RATE       int     = 44100
+	DEFAULT_FREQUENCY float64 = 440
+	DEFAULT_VOLUME    float64 = 0.25
+)
+
+// Beeper generates the samples for the buzzer. It plays a square wave at
+// Frequency, or the XO-CHIP audio pattern once SetPattern has been called.
+type Beeper struct {
+	SampleRate int
+	Frequency  float64
+
+	// Volume goes from 0 (silent) to 1 (full scale)
+	Volume float64
+	Muted  bool
+
+	// Where the next sample falls, in cycles of the square wave or bits
+	// of the pattern
+	phase float64
+
+	pattern     [AUDIO_PATTERN_SIZE]uint8
+	patternRate float64
+	usePattern  bool
+}
+
+func NewBeeper() Beeper {
+	return Beeper{
+		SampleRate: SAMPLE_RATE,
+		Frequency:  DEFAULT_FREQUENCY,
+		Volume:     DEFAULT_VOLUME,
+	}
+}
+
+// SetPattern plays the XO-CHIP 1-bit pattern instead of the square wave.
+// The pattern is played at 4000 * 2^((pitch - 64) / 48) bits per second.
+func (b *Beeper) SetPattern(pattern [AUDIO_PATTERN_SIZE]uint8, pitch uint8) {
+	b.pattern = pattern
+	b.patternRate = 4000 * math.Pow(2, (float64(pitch)-64)/48)
+	b.usePattern = true
+}
+
+// Reset starts the next tone from the beginning of its wave.
+func (b *Beeper) Reset() {
+	b.phase = 0
+}
+
+// Fill writes the next len(samples) samples of the tone.
+func (b *Beeper) Fill(samples []int16) {
+	amplitude := int16(b.Volume * math.MaxInt16)
+	if b.Muted {
+		amplitude = 0
+	}
+
+	if b.usePattern {
+		bits := float64(AUDIO_PATTERN_SIZE) * 8
+		step := b.patternRate / float64(b.SampleRate)
+
+		for i := range samples {
+			bit := int(b.phase)
+			if b.pattern[bit/8]>>(7-bit%8)&1 == 1 {
+				samples[i] = amplitude
+			} else {
+				samples[i] = -amplitude
+			}
+
+			b.phase = math.Mod(b.phase+step, bits)
+		}
+
+		return
+	}
+
+	step := b.Frequency / float64(b.SampleRate)
+
+	for i := range samples {
+		if b.phase < 0.5 {
+			samples[i] = amplitude
+		} else {
+			samples[i] = -amplitude
+		}
+
+		b.phase = math.Mod(b.phase+step, 1)
+	}
+}
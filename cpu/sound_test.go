@@ -0,0 +1,45 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBeeperFill(t *testing.T) {
+	t.Run("Square wave", func(t *testing.T) {
+		beeper := NewBeeper()
+		beeper.SampleRate = 8
+		beeper.Frequency = 2
+		beeper.Volume = 1
+
+		samples := make([]int16, 8)
+		beeper.Fill(samples)
+
+		high := int16(32767)
+		assert.Equal(t, []int16{high, high, -high, -high, high, high, -high, -high}, samples)
+	})
+
+	t.Run("Muted", func(t *testing.T) {
+		beeper := NewBeeper()
+		beeper.Muted = true
+
+		samples := make([]int16, 4)
+		beeper.Fill(samples)
+
+		assert.Equal(t, []int16{0, 0, 0, 0}, samples)
+	})
+
+	t.Run("XO-CHIP pattern", func(t *testing.T) {
+		beeper := NewBeeper()
+		beeper.SampleRate = 4000
+		beeper.Volume = 1
+		beeper.SetPattern([AUDIO_PATTERN_SIZE]uint8{0xA0}, DEFAULT_PITCH)
+
+		samples := make([]int16, 4)
+		beeper.Fill(samples)
+
+		high := int16(32767)
+		assert.Equal(t, []int16{high, -high, high, -high}, samples)
+	})
+}
@@ -0,0 +1,190 @@
+package cpu
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const STATE_VERSION uint16 = 3
+
+var stateMagic = [4]byte{'C', 'H', '8', 'S'}
+
+var (
+	ErrStateFormat  = errors.New("not a CHIP-8 save state")
+	ErrStateVersion = errors.New("unsupported save state version")
+	ErrStateRom     = errors.New("save state is for a different ROM")
+	ErrStateMachine = errors.New("save state is for a different machine")
+
+	ErrRPLFlagsFormat = errors.New("not an RPL flags file")
+)
+
+// A save state is a stateHeader, a stateBody and then the screen, all
+// little endian.
+type stateHeader struct {
+	Magic   [4]byte
+	Version uint16
+	Machine Machine
+	RomHash [20]byte
+}
+
+type stateBody struct {
+	ProgramCounter uint16
+	Ram            [RAM_SIZE]byte
+	VRegisters     [REGISTER_COUNT]uint8
+	IRegister      uint16
+	Stack          [STACK_SIZE]uint16
+	StackPointer   uint16
+	DelayTimer     uint16
+	SoundTimer     uint16
+	Opcode         uint16
+	Keys           [16]uint8
+	RPLFlags       [RPL_FLAG_COUNT]uint8
+	Planes         uint8
+	AudioPattern   [AUDIO_PATTERN_SIZE]uint8
+	Pitch          uint8
+	Exited         bool
+	HeldKey        uint8
+	KeyHeld        bool
+	RandomState    uint64
+	ScreenWidth    uint16
+	ScreenHeight   uint16
+}
+
+func (e *Emulator) SaveState(w io.Writer) error {
+	header := stateHeader{
+		Magic:   stateMagic,
+		Version: STATE_VERSION,
+		Machine: e.Machine,
+		RomHash: e.RomHash,
+	}
+
+	body := stateBody{
+		ProgramCounter: e.ProgramCounter,
+		Ram:            e.Ram,
+		VRegisters:     e.VRegisters,
+		IRegister:      e.IRegister,
+		Stack:          e.Stack,
+		StackPointer:   e.StackPointer,
+		DelayTimer:     e.DelayTimer,
+		SoundTimer:     e.SoundTimer,
+		Opcode:         e.Opcode,
+		Keys:           e.Keys,
+		RPLFlags:       e.RPLFlags,
+		Planes:         e.Planes,
+		AudioPattern:   e.AudioPattern,
+		Pitch:          e.Pitch,
+		Exited:         e.Exited,
+		HeldKey:        e.HeldKey,
+		KeyHeld:        e.KeyHeld,
+		RandomState:    e.Random.State,
+		ScreenWidth:    e.ScreenWidth,
+		ScreenHeight:   e.ScreenHeight,
+	}
+
+	for _, v := range []any{&header, &body, e.Screen} {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadState restores a state written by SaveState. The emulator must be
+// running the same ROM on the same machine. Nothing is changed if the
+// state can't be loaded.
+func (e *Emulator) LoadState(r io.Reader) error {
+	var header stateHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return fmt.Errorf("%w: %v", ErrStateFormat, err)
+	}
+
+	if header.Magic != stateMagic {
+		return ErrStateFormat
+	}
+
+	if header.Version != STATE_VERSION {
+		return fmt.Errorf("%w: %d", ErrStateVersion, header.Version)
+	}
+
+	if header.Machine != e.Machine {
+		return fmt.Errorf("%w: saved on %s, running %s", ErrStateMachine, header.Machine, e.Machine)
+	}
+
+	if header.RomHash != e.RomHash {
+		return fmt.Errorf("%w: saved with %x, running %x", ErrStateRom, header.RomHash, e.RomHash)
+	}
+
+	var body stateBody
+	if err := binary.Read(r, binary.LittleEndian, &body); err != nil {
+		return fmt.Errorf("%w: %v", ErrStateFormat, err)
+	}
+
+	if !(body.ScreenWidth == SCREEN_WIDTH && body.ScreenHeight == SCREEN_HEIGHT) &&
+		!(body.ScreenWidth == HIRES_SCREEN_WIDTH && body.ScreenHeight == HIRES_SCREEN_HEIGHT) {
+		return fmt.Errorf("%w: bad screen size %dx%d", ErrStateFormat, body.ScreenWidth, body.ScreenHeight)
+	}
+
+	if body.StackPointer > uint16(STACK_SIZE) {
+		return fmt.Errorf("%w: bad stack pointer %d", ErrStateFormat, body.StackPointer)
+	}
+
+	if body.Planes > 3 {
+		return fmt.Errorf("%w: bad planes %d", ErrStateFormat, body.Planes)
+	}
+
+	if body.HeldKey > 0xF {
+		return fmt.Errorf("%w: bad held key %d", ErrStateFormat, body.HeldKey)
+	}
+
+	screen := make([]uint8, int(body.ScreenWidth)*int(body.ScreenHeight))
+	if _, err := io.ReadFull(r, screen); err != nil {
+		return fmt.Errorf("%w: %v", ErrStateFormat, err)
+	}
+
+	e.ProgramCounter = body.ProgramCounter
+	e.Ram = body.Ram
+	e.VRegisters = body.VRegisters
+	e.IRegister = body.IRegister
+	e.Stack = body.Stack
+	e.StackPointer = body.StackPointer
+	e.DelayTimer = body.DelayTimer
+	e.SoundTimer = body.SoundTimer
+	e.Opcode = body.Opcode
+	e.Keys = body.Keys
+	e.RPLFlags = body.RPLFlags
+	e.Planes = body.Planes
+	e.AudioPattern = body.AudioPattern
+	e.Pitch = body.Pitch
+	e.Exited = body.Exited
+	e.HeldKey = body.HeldKey
+	e.KeyHeld = body.KeyHeld
+	e.Random.State = body.RandomState
+	e.ScreenWidth = body.ScreenWidth
+	e.ScreenHeight = body.ScreenHeight
+	e.Screen = screen
+	e.err = nil
+
+	return nil
+}
+
+// SaveRPLFlags writes the RPL flags, one byte each, so they can be kept
+// between runs as the HP48 kept them.
+func (e *Emulator) SaveRPLFlags(w io.Writer) error {
+	_, err := w.Write(e.RPLFlags[:])
+	return err
+}
+
+// LoadRPLFlags reads flags written by SaveRPLFlags.
+func (e *Emulator) LoadRPLFlags(r io.Reader) error {
+	var flags [RPL_FLAG_COUNT]uint8
+	if _, err := io.ReadFull(r, flags[:]); err != nil {
+		return fmt.Errorf("%w: %v", ErrRPLFlagsFormat, err)
+	}
+
+	e.RPLFlags = flags
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package cpu
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveState(t *testing.T) {
+	t.Run("Restores the emulator", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Machine = MachineSuperChip
+		emu.RomHash = [20]byte{1, 2, 3}
+		emu.ProgramCounter = 0x240
+		emu.Ram[0x300] = 0xAB
+		emu.VRegisters[3] = 7
+		emu.IRegister = 0x300
+		emu.Push(0x222)
+		emu.DelayTimer = 30
+		emu.SoundTimer = 5
+		emu.Keys[0xA] = 1
+		emu.SetResolution(true)
+		emu.Screen[500] = 1
+
+		var buf bytes.Buffer
+		assert.NoError(t, emu.SaveState(&buf))
+
+		restored := NewEmulator()
+		restored.Machine = MachineSuperChip
+		restored.RomHash = [20]byte{1, 2, 3}
+		assert.NoError(t, restored.LoadState(&buf))
+
+		assert.Equal(t, emu, restored)
+	})
+
+	t.Run("Replays the same random numbers", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Random = NewRand(7)
+
+		var buf bytes.Buffer
+		assert.NoError(t, emu.SaveState(&buf))
+		emu.Decode(0xC0FF)
+
+		restored := NewEmulator()
+		assert.NoError(t, restored.LoadState(&buf))
+		restored.Decode(0xC0FF)
+
+		assert.Equal(t, emu.VRegisters[0], restored.VRegisters[0])
+	})
+
+	t.Run("Rejects a different ROM", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.RomHash = [20]byte{1}
+
+		var buf bytes.Buffer
+		assert.NoError(t, emu.SaveState(&buf))
+
+		other := NewEmulator()
+		other.RomHash = [20]byte{2}
+		other.ProgramCounter = 0x300
+		err := other.LoadState(&buf)
+
+		assert.True(t, errors.Is(err, ErrStateRom))
+		assert.Equal(t, uint16(0x300), other.ProgramCounter)
+	})
+
+	t.Run("Rejects a different machine", func(t *testing.T) {
+		emu := NewEmulator()
+
+		var buf bytes.Buffer
+		assert.NoError(t, emu.SaveState(&buf))
+
+		other := NewEmulator()
+		other.Machine = MachineXOChip
+		err := other.LoadState(&buf)
+
+		assert.True(t, errors.Is(err, ErrStateMachine))
+	})
+
+	t.Run("Rejects other files", func(t *testing.T) {
+		emu := NewEmulator()
+		err := emu.LoadState(bytes.NewReader([]byte("not a save state at all")))
+
+		assert.True(t, errors.Is(err, ErrStateFormat))
+	})
+
+	bad_states := []struct {
+		name    string
+		corrupt func(e *Emulator)
+		err     string
+	}{
+		{"stack pointer", func(e *Emulator) { e.StackPointer = uint16(STACK_SIZE) + 1 }, "bad stack pointer 17"},
+		{"planes", func(e *Emulator) { e.Planes = 4 }, "bad planes 4"},
+		{"held key", func(e *Emulator) { e.HeldKey, e.KeyHeld = 0x10, true }, "bad held key 16"},
+	}
+
+	for _, test := range bad_states {
+		t.Run("Rejects a bad "+test.name, func(t *testing.T) {
+			emu := NewEmulator()
+			test.corrupt(&emu)
+
+			var buf bytes.Buffer
+			assert.NoError(t, emu.SaveState(&buf))
+
+			other := NewEmulator()
+			other.ProgramCounter = 0x300
+			err := other.LoadState(&buf)
+
+			assert.ErrorIs(t, err, ErrStateFormat)
+			assert.ErrorContains(t, err, test.err)
+			assert.Equal(t, uint16(0x300), other.ProgramCounter)
+		})
+	}
+}
+
+func TestSaveRPLFlags(t *testing.T) {
+	t.Run("Restores the flags", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.Machine = MachineSuperChip
+		emu.VRegisters[0] = 0x12
+		emu.VRegisters[7] = 0x34
+		emu.Decode(0xF775)
+
+		var buf bytes.Buffer
+		assert.NoError(t, emu.SaveRPLFlags(&buf))
+
+		restored := NewEmulator()
+		restored.Machine = MachineSuperChip
+		assert.NoError(t, restored.LoadRPLFlags(&buf))
+		restored.Decode(0xF785)
+
+		assert.Equal(t, uint8(0x12), restored.VRegisters[0])
+		assert.Equal(t, uint8(0x34), restored.VRegisters[7])
+	})
+
+	t.Run("Rejects a short file", func(t *testing.T) {
+		emu := NewEmulator()
+		emu.RPLFlags[0] = 9
+		err := emu.LoadRPLFlags(bytes.NewReader([]byte{1, 2, 3}))
+
+		assert.ErrorIs(t, err, ErrRPLFlagsFormat)
+		assert.Equal(t, uint8(9), emu.RPLFlags[0])
+	})
+}
@@ -0,0 +1,423 @@
+package cpu
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Terminals only send a key when it goes down and then again as it
+// repeats, so a key counts as held until this long after the last time it
+// was sent
+const DEFAULT_KEY_RELEASE time.Duration = 250 * time.Millisecond
+
+// Terminal is a Frontend that draws in a terminal, for when there's no
+// window, such as over SSH. Each character is a Unicode half block showing
+// two pixels one above the other, so a 64x32 screen takes 64x16
+// characters. The buzzer rings the terminal bell. Esc or Ctrl+C quits.
+type Terminal struct {
+	// Input defaults to os.Stdin and Output to os.Stdout. When Input is a
+	// terminal it's put in raw mode while the emulator runs.
+	Input  io.Reader
+	Output io.Writer
+
+	// Keymap defaults to the qwerty preset. Only keys a terminal can send
+	// work, which are letters, digits, punctuation, Space and the arrows.
+	Keymap Keymap
+
+	// KeyRelease is how long a key stays pressed after the terminal last
+	// sent it, 0 means DEFAULT_KEY_RELEASE
+	KeyRelease time.Duration
+
+	// Timing, InstructionsPerSecond, Record, Replay and Capture are
+	// passed on to the Runner, 0 instructions per second means DEFAULT_IPS
+	Timing                Timing
+	InstructionsPerSecond int
+	Record                *Movie
+	Replay                *Movie
+	Capture               *Capture
+
+	// Debug, when set, is passed on to the Runner with the terminal out of
+	// raw mode. It's given what's typed from then on as its input, since
+	// Terminal is already reading Input.
+	Debug func(emulator *Emulator, input io.Reader)
+
+	// Colors are the background and pixel colors, as for Display
+	Colors []color.RGBA
+
+	// Screenshot, when set, gets the last frame as a PNG when the user
+	// quits, ScreenshotScale image pixels to a CHIP-8 pixel
+	Screenshot      io.Writer
+	ScreenshotScale int
+
+	palette color.Palette
+	restore func()
+	input   chan []byte
+	done    chan struct{}
+	held    map[uint8]time.Time
+	last    []uint8
+	frame   bytes.Buffer
+	err     error
+
+	now func() time.Time
+}
+
+// Run draws the emulator in the terminal until the user quits. It returns
+// errors from the terminal and from the ROM, and leaves the emulator as it
+// stopped.
+func (t *Terminal) Run(emulator *Emulator) error {
+	if t.Input == nil {
+		t.Input = os.Stdin
+	}
+
+	if t.Output == nil {
+		t.Output = os.Stdout
+	}
+
+	if t.Keymap.Keys == nil {
+		t.Keymap, _ = KeymapByName("qwerty")
+	}
+
+	if t.KeyRelease <= 0 {
+		t.KeyRelease = DEFAULT_KEY_RELEASE
+	}
+
+	if t.now == nil {
+		t.now = time.Now
+	}
+
+	t.palette = screenPalette(t.Colors)
+	t.held = map[uint8]time.Time{}
+
+	if err := t.enterRawMode(); err != nil {
+		return err
+	}
+	defer t.leaveRawMode()
+
+	t.input = make(chan []byte, 16)
+	t.done = make(chan struct{})
+	defer close(t.done)
+	go t.read()
+
+	// Clear the terminal and hide the cursor, then put it all back after
+	fmt.Fprint(t.Output, "\x1b[2J\x1b[?25l")
+	defer fmt.Fprint(t.Output, "\x1b[0m\x1b[?25h\r\n")
+
+	runner := NewRunner(t)
+	runner.Timing = t.Timing
+	if t.InstructionsPerSecond > 0 {
+		runner.InstructionsPerSecond = t.InstructionsPerSecond
+	}
+	runner.Record = t.Record
+	runner.Replay = t.Replay
+	runner.Capture = t.Capture
+	if t.Debug != nil {
+		runner.Debug = t.debug
+	}
+	if err := runner.Run(emulator); err != nil {
+		return err
+	}
+
+	if t.Screenshot != nil {
+		if err := WriteScreenshot(t.Screenshot, emulator.Screen, emulator.ScreenWidth, emulator.ScreenHeight, t.ScreenshotScale, t.Colors); err != nil {
+			return err
+		}
+	}
+
+	return t.err
+}
+
+// enterRawMode puts Input in raw mode when it's a terminal.
+func (t *Terminal) enterRawMode() error {
+	file, ok := t.Input.(*os.File)
+	if !ok || !isTerminal(file) {
+		return nil
+	}
+
+	restore, err := rawMode(file)
+	if err != nil {
+		return err
+	}
+	t.restore = restore
+
+	return nil
+}
+
+func (t *Terminal) leaveRawMode() {
+	if t.restore != nil {
+		t.restore()
+		t.restore = nil
+	}
+}
+
+// debug runs Debug on a normal terminal, then takes the screen back.
+func (t *Terminal) debug(emulator *Emulator) {
+	t.leaveRawMode()
+	fmt.Fprint(t.Output, "\x1b[0m\x1b[?25h\x1b[2J\x1b[H")
+
+	t.Debug(emulator, &terminalInput{terminal: t})
+
+	if err := t.enterRawMode(); err != nil && t.err == nil {
+		t.err = err
+	}
+	fmt.Fprint(t.Output, "\x1b[2J\x1b[?25l")
+	t.last = nil
+}
+
+// terminalInput is what read gets from Input, for the debugger.
+type terminalInput struct {
+	terminal *Terminal
+	pending  []byte
+}
+
+func (in *terminalInput) Read(p []byte) (int, error) {
+	if len(in.pending) == 0 {
+		if in.terminal.input == nil {
+			return 0, io.EOF
+		}
+
+		chunk, ok := <-in.terminal.input
+		if !ok {
+			in.terminal.input = nil
+			return 0, io.EOF
+		}
+		in.pending = chunk
+	}
+
+	n := copy(p, in.pending)
+	in.pending = in.pending[n:]
+
+	return n, nil
+}
+
+// read passes chunks of input to PollInput until Input ends or Run
+// returns. A read that's already waiting on Input ends with the next key.
+func (t *Terminal) read() {
+	for {
+		buffer := make([]byte, 64)
+
+		n, err := t.Input.Read(buffer)
+
+		// The channel may have room, so look for Run having returned first
+		select {
+		case <-t.done:
+			return
+		default:
+		}
+
+		if n > 0 {
+			select {
+			case t.input <- buffer[:n]:
+			case <-t.done:
+				return
+			}
+		}
+
+		if err != nil {
+			close(t.input)
+			return
+		}
+	}
+}
+
+// Present redraws the screen when it has changed since the last frame.
+func (t *Terminal) Present(screen []uint8, width uint16, height uint16) {
+	if t.err != nil || bytes.Equal(screen, t.last) {
+		return
+	}
+
+	t.frame.Reset()
+
+	// Clear what's left of a bigger screen after a switch out of hires
+	if t.last != nil && len(screen) != len(t.last) {
+		t.frame.WriteString("\x1b[2J")
+	}
+	t.frame.WriteString("\x1b[H")
+	t.last = append(t.last[:0], screen...)
+
+	drawHalfBlocks(&t.frame, screen, width, height, t.palette)
+
+	_, t.err = t.Output.Write(t.frame.Bytes())
+}
+
+// drawHalfBlocks writes screen as rows of upper half blocks, the top pixel
+// in the foreground color and the bottom one in the background color.
+// Colors are only sent when they change.
+func drawHalfBlocks(out *bytes.Buffer, screen []uint8, width uint16, height uint16, palette color.Palette) {
+	foreground, background := -1, -1
+
+	for y := 0; y < int(height); y += 2 {
+		for x := 0; x < int(width); x++ {
+			top := int(screen[y*int(width)+x] & 0x3)
+			bottom := 0
+			if y+1 < int(height) {
+				bottom = int(screen[(y+1)*int(width)+x] & 0x3)
+			}
+
+			if top != foreground {
+				r, g, b, _ := palette[top].RGBA()
+				fmt.Fprintf(out, "\x1b[38;2;%d;%d;%dm", r>>8, g>>8, b>>8)
+				foreground = top
+			}
+
+			if bottom != background {
+				r, g, b, _ := palette[bottom].RGBA()
+				fmt.Fprintf(out, "\x1b[48;2;%d;%d;%dm", r>>8, g>>8, b>>8)
+				background = bottom
+			}
+
+			out.WriteString("▀")
+		}
+
+		// Raw mode doesn't turn \n into \r\n, and the colors are reset so
+		// they don't spill into the rest of the line
+		out.WriteString("\x1b[0m\r\n")
+		foreground, background = -1, -1
+	}
+}
+
+// PollInput presses the keys the terminal has sent since the last frame,
+// and releases the ones it hasn't sent for KeyRelease.
+func (t *Terminal) PollInput(emulator *Emulator) bool {
+	now := t.now()
+
+	for polling := true; polling; {
+		select {
+		case chunk, ok := <-t.input:
+			if !ok {
+				// Input has ended, so carry on without it
+				t.input = nil
+				break
+			}
+
+			names, quit := terminalKeys(chunk)
+			if quit {
+				return false
+			}
+
+			for _, name := range names {
+				if key, ok := t.Keymap.Keys[name]; ok {
+					emulator.Key(key, 1)
+					t.held[key] = now
+				}
+			}
+
+		default:
+			polling = false
+		}
+	}
+
+	for key, pressed := range t.held {
+		if now.Sub(pressed) >= t.KeyRelease {
+			emulator.Key(key, 0)
+			delete(t.held, key)
+		}
+	}
+
+	return t.err == nil
+}
+
+// The escape sequences terminals send for the arrow keys
+var arrowKeys = map[string]string{
+	"\x1b[A": "Up",
+	"\x1b[B": "Down",
+	"\x1b[C": "Right",
+	"\x1b[D": "Left",
+	"\x1bOA": "Up",
+	"\x1bOB": "Down",
+	"\x1bOC": "Right",
+	"\x1bOD": "Left",
+}
+
+// terminalKeys turns what a terminal sent into SDL key names, which is
+// what a Keymap uses. It returns true when Esc or Ctrl+C was pressed.
+// Escape sequences other than the arrows are skipped.
+func terminalKeys(chunk []byte) ([]string, bool) {
+	names := []string{}
+
+	for i := 0; i < len(chunk); i++ {
+		c := chunk[i]
+
+		switch {
+		case c == 0x03:
+			return names, true
+
+		case c == 0x1b:
+			if i+1 == len(chunk) || (chunk[i+1] != '[' && chunk[i+1] != 'O') {
+				return names, true
+			}
+
+			if i+2 < len(chunk) {
+				if name, ok := arrowKeys[string(chunk[i:i+3])]; ok {
+					names = append(names, name)
+				}
+			}
+
+			// Skip to the letter or ~ that ends the sequence
+			i += 2
+			for i < len(chunk) && !isSequenceEnd(chunk[i]) {
+				i++
+			}
+
+		case c == ' ':
+			names = append(names, "Space")
+
+		case c > ' ' && c < 0x7f:
+			names = append(names, strings.ToUpper(string(c)))
+		}
+	}
+
+	return names, false
+}
+
+func isSequenceEnd(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c == '~'
+}
+
+// PlayTone rings the bell as the buzzer starts. Terminals can't hold a
+// tone, so it doesn't last.
+func (t *Terminal) PlayTone(on bool) {
+	if on && t.err == nil {
+		_, t.err = t.Output.Write([]byte("\a"))
+	}
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// rawMode switches the terminal to raw mode with stty, so keys arrive as
+// they're pressed and aren't echoed. It returns a function that puts the
+// terminal back the way it was.
+func rawMode(file *os.File) (func(), error) {
+	stty := func(args ...string) (string, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = file
+
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("could not set up the terminal: stty %s: %w", strings.Join(args, " "), err)
+		}
+
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	state, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		stty(state)
+	}, nil
+}
@@ -0,0 +1,150 @@
+package cpu
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrawHalfBlocks(t *testing.T) {
+	// 2x2 screen, the left column on
+	screen := []uint8{1, 0, 1, 0}
+
+	var out bytes.Buffer
+	drawHalfBlocks(&out, screen, 2, 2, screenPalette(nil))
+
+	assert.Equal(t, ""+
+		"\x1b[38;2;15;255;80m\x1b[48;2;15;255;80m▀"+
+		"\x1b[38;2;0;0;0m\x1b[48;2;0;0;0m▀"+
+		"\x1b[0m\r\n", out.String())
+
+	t.Run("Only sends colors that change", func(t *testing.T) {
+		var out bytes.Buffer
+		drawHalfBlocks(&out, make([]uint8, 64*32), 64, 32, screenPalette(nil))
+
+		assert.Equal(t, 16, strings.Count(out.String(), "\x1b[38;2;"))
+		assert.Equal(t, 64*16, strings.Count(out.String(), "▀"))
+	})
+
+	t.Run("Leaves the bottom of an odd row blank", func(t *testing.T) {
+		var out bytes.Buffer
+		drawHalfBlocks(&out, []uint8{1}, 1, 1, screenPalette(nil))
+
+		assert.Contains(t, out.String(), "\x1b[48;2;0;0;0m▀")
+	})
+}
+
+func TestTerminalKeys(t *testing.T) {
+	names, quit := terminalKeys([]byte("q1 \x1b[A\x1b[1;5C,"))
+	assert.False(t, quit)
+	assert.Equal(t, []string{"Q", "1", "Space", "Up", ","}, names)
+
+	_, quit = terminalKeys([]byte("w\x1b"))
+	assert.True(t, quit)
+
+	_, quit = terminalKeys([]byte{0x03})
+	assert.True(t, quit)
+}
+
+func TestTerminalPollInput(t *testing.T) {
+	now := time.Unix(0, 0)
+	keymap, _ := KeymapByName("qwerty")
+
+	terminal := Terminal{Keymap: keymap, KeyRelease: 100 * time.Millisecond}
+	terminal.held = map[uint8]time.Time{}
+	terminal.input = make(chan []byte, 4)
+	terminal.now = func() time.Time { return now }
+
+	emu := NewEmulator()
+
+	terminal.input <- []byte("w")
+	assert.True(t, terminal.PollInput(&emu))
+	assert.Equal(t, uint8(1), emu.Keys[0x5])
+
+	t.Run("Holds a key until the timeout", func(t *testing.T) {
+		now = now.Add(50 * time.Millisecond)
+		terminal.PollInput(&emu)
+		assert.Equal(t, uint8(1), emu.Keys[0x5])
+
+		now = now.Add(50 * time.Millisecond)
+		terminal.PollInput(&emu)
+		assert.Equal(t, uint8(0), emu.Keys[0x5])
+	})
+
+	t.Run("Keeps running when input ends", func(t *testing.T) {
+		close(terminal.input)
+		assert.True(t, terminal.PollInput(&emu))
+	})
+}
+
+func TestTerminalRun(t *testing.T) {
+	emu := NewEmulator()
+	// 0x200: LD V0, 0x20, 0x202: LD ST, V0, 0x204: JP 0x204
+	copy(emu.Ram[START_ADDRESS:], []uint8{0x60, 0x20, 0xF0, 0x18, 0x12, 0x04})
+
+	var out bytes.Buffer
+	terminal := Terminal{Input: strings.NewReader("\x03"), Output: &out}
+	assert.NoError(t, terminal.Run(&emu))
+
+	assert.Contains(t, out.String(), "\a")
+	assert.Contains(t, out.String(), "▀")
+	assert.True(t, strings.HasSuffix(out.String(), "\x1b[?25h\r\n"))
+}
+
+// typedKeys is Input that waits for each key to be typed, as a terminal does.
+type typedKeys chan []byte
+
+func (k typedKeys) Read(p []byte) (int, error) {
+	return copy(p, <-k), nil
+}
+
+func TestTerminalStopsReading(t *testing.T) {
+	emu := NewEmulator()
+	// 0x200: JP 0x200
+	copy(emu.Ram[START_ADDRESS:], []uint8{0x12, 0x00})
+
+	keys := make(typedKeys)
+	go func() { keys <- []byte("\x03") }()
+
+	terminal := Terminal{Input: keys, Output: &bytes.Buffer{}}
+	assert.NoError(t, terminal.Run(&emu))
+
+	// The read that was waiting gets the next key, then gives up
+	select {
+	case keys <- []byte("a"):
+	case <-time.After(time.Second):
+		t.Fatal("nothing was reading input")
+	}
+
+	select {
+	case keys <- []byte("b"):
+		t.Error("input is still being read after Run returned")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTerminalDebug(t *testing.T) {
+	emu := NewEmulator()
+	emu.MemoryPolicy = MemoryTrap
+	emu.IRegister = 0xFFF
+	// 0x200: LD [I], V1, 0x202: JP 0x202
+	copy(emu.Ram[START_ADDRESS:], []uint8{0xF1, 0x55, 0x12, 0x02})
+
+	// The debugger gets what's typed while it runs, and the terminal gets
+	// the Ctrl+C after it
+	input := io.MultiReader(strings.NewReader("set I 0x300\nstep\nquit\n"), strings.NewReader("\x03"))
+
+	var debugged bytes.Buffer
+	terminal := Terminal{Input: input, Output: &bytes.Buffer{}}
+	terminal.Debug = func(emulator *Emulator, input io.Reader) {
+		NewDebugger(emulator, input, &debugged).Run()
+	}
+
+	assert.NoError(t, terminal.Run(&emu))
+	assert.Equal(t, uint16(0x202), emu.ProgramCounter)
+	assert.Contains(t, debugged.String(), "PC 0x202")
+}
@@ -0,0 +1,91 @@
+package cpu
+
+import (
+	"fmt"
+)
+
+const (
+	DEFAULT_IPS int = TICKS_PER_FRAME * 60
+	MIN_IPS     int = 60
+	MAX_IPS     int = 1000000
+
+	// Fast-forward runs this many frames in the time of one
+	FAST_FORWARD_SPEED int = 4
+
+	// A Runner that falls further behind than this skips ahead instead
+	// of trying to catch up
+	MAX_CATCH_UP_FRAMES int = 5
+
+	// Microseconds of VIP time in each frame
+	VIP_FRAME_TIME int = 1000000 / 60
+)
+
+// Timing decides how many instructions run in each frame.
+type Timing uint8
+
+const (
+	// TimingFixed runs InstructionsPerSecond / 60 instructions a frame
+	TimingFixed Timing = iota
+
+	// TimingVIP runs instructions until they add up to a frame on a
+	// COSMAC VIP, using vipInstructionTime
+	TimingVIP
+)
+
+var timingNames = map[string]Timing{
+	"fixed": TimingFixed,
+	"vip":   TimingVIP,
+}
+
+func TimingByName(name string) (Timing, error) {
+	timing, ok := timingNames[name]
+	if !ok {
+		return TimingFixed, fmt.Errorf("unknown timing %q (choose from fixed, vip)", name)
+	}
+
+	return timing, nil
+}
+
+// vipInstructionTime approximates how many microseconds the CHIP-8
+// interpreter on a COSMAC VIP takes to run opcode. The real times also
+// depend on the operands and on where the display interrupt lands, so
+// this gets the speed of a game right rather than every instruction.
+func vipInstructionTime(opcode uint16) int {
+	switch opcode & 0xF000 {
+	case 0x0000:
+		if opcode == 0x00E0 {
+			return 109
+		}
+		return 105
+	case 0x1000, 0x2000, 0xB000:
+		return 105
+	case 0x3000, 0x4000, 0xA000:
+		return 55
+	case 0x5000, 0x9000, 0xE000:
+		return 73
+	case 0x6000:
+		return 27
+	case 0x7000:
+		return 45
+	case 0x8000:
+		return 200
+	case 0xC000:
+		return 164
+	case 0xD000:
+		// About 68us a row plus setup, VIP sprites are 8 pixels wide
+		return 1000 + 68*int(opcode&0x000F)
+	}
+
+	switch opcode & 0x00FF {
+	case 0x1E:
+		return 86
+	case 0x29:
+		return 91
+	case 0x33:
+		return 927
+	case 0x55, 0x65:
+		return 605 + 64*int(opcode&0x0F00>>8)
+	}
+
+	return 45
+}
@@ -0,0 +1,168 @@
+package cpu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Tracer is told about every instruction the emulator runs. Setting
+// Emulator.Tracer turns tracing on. When it's nil Tick does no extra work.
+type Tracer interface {
+	Trace(entry TraceEntry)
+}
+
+// TraceEntry is the state after one instruction. Instruction is decoded
+// from memory as it was before the instruction ran, and Previous holds the
+// V registers from then, so the two can be compared.
+type TraceEntry struct {
+	Address      uint16
+	Instruction  Instruction
+	Previous     [REGISTER_COUNT]uint8
+	VRegisters   [REGISTER_COUNT]uint8
+	IRegister    uint16
+	StackPointer uint16
+	DelayTimer   uint16
+	SoundTimer   uint16
+}
+
+// Changed lists the V registers the instruction changed, lowest first.
+func (t TraceEntry) Changed() []uint8 {
+	changed := []uint8{}
+
+	for r := range t.VRegisters {
+		if t.VRegisters[r] != t.Previous[r] {
+			changed = append(changed, uint8(r))
+		}
+	}
+
+	return changed
+}
+
+// TRACE_END is the To of an address range that has no end.
+const TRACE_END uint16 = 0xFFFF
+
+type TraceFormat uint8
+
+const (
+	TraceText TraceFormat = iota
+	TraceJSON
+)
+
+var traceFormatNames = map[string]TraceFormat{
+	"text":  TraceText,
+	"jsonl": TraceJSON,
+}
+
+func TraceFormatByName(name string) (TraceFormat, error) {
+	format, ok := traceFormatNames[name]
+	if !ok {
+		names := make([]string, 0, len(traceFormatNames))
+		for name := range traceFormatNames {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return TraceText, fmt.Errorf("unknown trace format %q (choose from %s)", name, strings.Join(names, ", "))
+	}
+
+	return format, nil
+}
+
+// TraceWriter is a Tracer that writes one line per instruction to Output,
+// either as text or as JSON Lines. Instructions outside From to To, or
+// whose class isn't in Classes, are skipped.
+type TraceWriter struct {
+	Output io.Writer
+	Format TraceFormat
+
+	// Ranged limits tracing to instructions from From to To, inclusive.
+	// A To of TRACE_END runs to the end of memory.
+	Ranged bool
+	From   uint16
+	To     uint16
+
+	// Classes limits tracing to opcodes whose first nibble is listed, so
+	// []uint8{0xD} only traces draws. Empty traces every class.
+	Classes []uint8
+
+	err error
+}
+
+type traceJSON struct {
+	PC          uint16           `json:"pc"`
+	Opcode      uint16           `json:"opcode"`
+	Instruction string           `json:"instruction"`
+	Changed     map[string]uint8 `json:"changed"`
+	I           uint16           `json:"i"`
+	SP          uint16           `json:"sp"`
+	DT          uint16           `json:"dt"`
+	ST          uint16           `json:"st"`
+}
+
+func (w *TraceWriter) Trace(entry TraceEntry) {
+	if w.err != nil || !w.matches(entry) {
+		return
+	}
+
+	if w.Format == TraceJSON {
+		line := traceJSON{
+			PC:          entry.Address,
+			Opcode:      entry.Instruction.Opcode,
+			Instruction: entry.Instruction.Cowgod(),
+			Changed:     map[string]uint8{},
+			I:           entry.IRegister,
+			SP:          entry.StackPointer,
+			DT:          entry.DelayTimer,
+			ST:          entry.SoundTimer,
+		}
+
+		for _, r := range entry.Changed() {
+			line.Changed[fmt.Sprintf("V%X", r)] = entry.VRegisters[r]
+		}
+
+		data, err := json.Marshal(line)
+		if err == nil {
+			_, err = fmt.Fprintf(w.Output, "%s\n", data)
+		}
+		w.err = err
+
+		return
+	}
+
+	changes := ""
+	for _, r := range entry.Changed() {
+		changes += fmt.Sprintf(" V%X=0x%02X", r, entry.VRegisters[r])
+	}
+
+	_, w.err = fmt.Fprintf(w.Output, "0x%03X %04X %-24s I=0x%03X SP=%d DT=%d ST=%d%s\n",
+		entry.Address, entry.Instruction.Opcode, entry.Instruction.Cowgod(),
+		entry.IRegister, entry.StackPointer, entry.DelayTimer, entry.SoundTimer, changes)
+}
+
+// Err returns the first error from writing to Output. Nothing more is
+// written after one.
+func (w *TraceWriter) Err() error {
+	return w.err
+}
+
+func (w *TraceWriter) matches(entry TraceEntry) bool {
+	if w.Ranged && (entry.Address < w.From || entry.Address > w.To) {
+		return false
+	}
+
+	if len(w.Classes) == 0 {
+		return true
+	}
+
+	class := uint8(entry.Instruction.Opcode >> 12)
+	for _, c := range w.Classes {
+		if c == class {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,107 @@
+package cpu
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingTracer struct {
+	entries []TraceEntry
+}
+
+func (r *recordingTracer) Trace(entry TraceEntry) {
+	r.entries = append(r.entries, entry)
+}
+
+// 0x200: LD V0, 0x05, 0x202: LD I, 0x300, 0x204: ADD V0, V0, 0x206: DRW V0, V1, 1
+var traceRom = []uint8{0x60, 0x05, 0xA3, 0x00, 0x80, 0x04, 0xD0, 0x11}
+
+func TestTracer(t *testing.T) {
+	emu := NewEmulator()
+	copy(emu.Ram[START_ADDRESS:], traceRom)
+	emu.VRegisters[0xF] = 1
+
+	tracer := recordingTracer{}
+	emu.Tracer = &tracer
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, emu.Tick())
+	}
+
+	assert.Len(t, tracer.entries, 3)
+	assert.Equal(t, uint16(0x200), tracer.entries[0].Address)
+	assert.Equal(t, OpLoadByte, tracer.entries[0].Instruction.Op)
+	assert.Equal(t, []uint8{0}, tracer.entries[0].Changed())
+	assert.Equal(t, uint16(0x300), tracer.entries[1].IRegister)
+	assert.Equal(t, []uint8{}, tracer.entries[1].Changed())
+	assert.Equal(t, []uint8{0, 0xF}, tracer.entries[2].Changed())
+}
+
+func TestTraceWriter(t *testing.T) {
+	run := func(w *TraceWriter) string {
+		var out bytes.Buffer
+		w.Output = &out
+
+		emu := NewEmulator()
+		copy(emu.Ram[START_ADDRESS:], traceRom)
+		emu.VRegisters[0xF] = 1
+		emu.Tracer = w
+
+		for i := 0; i < 4; i++ {
+			assert.NoError(t, emu.Tick())
+		}
+
+		assert.NoError(t, w.Err())
+		return out.String()
+	}
+
+	t.Run("Text", func(t *testing.T) {
+		assert.Equal(t, ""+
+			"0x200 6005 LD V0, 0x05              I=0x000 SP=0 DT=0 ST=0 V0=0x05\n"+
+			"0x202 A300 LD I, 0x300              I=0x300 SP=0 DT=0 ST=0\n"+
+			"0x204 8004 ADD V0, V0               I=0x300 SP=0 DT=0 ST=0 V0=0x0A VF=0x00\n"+
+			"0x206 D011 DRW V0, V1, 1            I=0x300 SP=0 DT=0 ST=0\n",
+			run(&TraceWriter{}))
+	})
+
+	t.Run("JSON Lines", func(t *testing.T) {
+		assert.Equal(t,
+			`{"pc":512,"opcode":24581,"instruction":"LD V0, 0x05","changed":{"V0":5},"i":0,"sp":0,"dt":0,"st":0}`+"\n",
+			run(&TraceWriter{Format: TraceJSON, Ranged: true, To: 0x200}))
+	})
+
+	t.Run("Address range", func(t *testing.T) {
+		out := run(&TraceWriter{Ranged: true, From: 0x202, To: 0x204})
+		assert.Equal(t, 2, bytes.Count([]byte(out), []byte("\n")))
+		assert.Contains(t, out, "0x202 ")
+		assert.Contains(t, out, "0x204 ")
+	})
+
+	t.Run("Address range with no end", func(t *testing.T) {
+		out := run(&TraceWriter{Ranged: true, From: 0x204, To: TRACE_END})
+		assert.Equal(t, 2, bytes.Count([]byte(out), []byte("\n")))
+		assert.Contains(t, out, "0x204 ")
+		assert.Contains(t, out, "0x206 ")
+	})
+
+	t.Run("Address range of address 0", func(t *testing.T) {
+		assert.Equal(t, "", run(&TraceWriter{Ranged: true, From: 0, To: 0}))
+	})
+
+	t.Run("Opcode class", func(t *testing.T) {
+		assert.Equal(t,
+			"0x206 D011 DRW V0, V1, 1            I=0x300 SP=0 DT=0 ST=0\n",
+			run(&TraceWriter{Classes: []uint8{0xD}}))
+	})
+}
+
+func TestTraceFormatByName(t *testing.T) {
+	format, err := TraceFormatByName("jsonl")
+	assert.NoError(t, err)
+	assert.Equal(t, TraceJSON, format)
+
+	_, err = TraceFormatByName("xml")
+	assert.Error(t, err)
+}
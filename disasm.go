@@ -0,0 +1,43 @@
+package main
+
+import (
+	"chip-8/cpu"
+	"chip-8/disasm"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// disasmCommand implements "chip-8 disasm rom.ch8".
+func disasmCommand(args []string) {
+	flags := flag.NewFlagSet("disasm", flag.ExitOnError)
+	syntaxName := flags.String("syntax", "cowgod", "assembly syntax: cowgod or octo")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: chip-8 disasm [-syntax cowgod|octo] rom.ch8")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	syntax, err := disasm.SyntaxByName(*syntaxName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	rom, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	lines := disasm.Disassemble(rom, cpu.START_ADDRESS)
+	if err := disasm.Write(os.Stdout, lines, syntax); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
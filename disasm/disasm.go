@@ -0,0 +1,184 @@
+// Package disasm turns CHIP-8 ROMs back into assembly listings.
+package disasm
+
+import (
+	"chip-8/cpu"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Syntax is the assembly dialect a listing is written in.
+type Syntax uint8
+
+const (
+	Cowgod Syntax = iota
+	Octo
+)
+
+// DATA_PER_LINE is how many bytes of data go on one line of a listing.
+const DATA_PER_LINE = 8
+
+var syntaxNames = map[string]Syntax{
+	"cowgod": Cowgod,
+	"octo":   Octo,
+}
+
+func SyntaxByName(name string) (Syntax, error) {
+	syntax, ok := syntaxNames[name]
+	if !ok {
+		names := make([]string, 0, len(syntaxNames))
+		for name := range syntaxNames {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return Cowgod, fmt.Errorf("unknown syntax %q (choose from %s)", name, strings.Join(names, ", "))
+	}
+
+	return syntax, nil
+}
+
+// Line is one line of a listing. It is either an instruction or, when Data
+// is set, a run of bytes that no path through the code reaches.
+type Line struct {
+	Address     uint16
+	Bytes       []byte
+	Data        bool
+	Instruction cpu.Instruction
+}
+
+// Disassemble lists rom as it would be laid out in memory from origin.
+// It follows jumps, calls and skips from origin to work out which bytes are
+// code. Everything else, such as sprites, is listed as data. Code only
+// reached through a computed jump (Bnnn) is also listed as data.
+func Disassemble(rom []byte, origin uint16) []Line {
+	end := int(origin) + len(rom)
+	ram := make([]byte, end)
+	copy(ram[origin:], rom)
+
+	starts := make([]bool, end)
+	pending := []int{int(origin)}
+
+	for len(pending) > 0 {
+		address := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if address < int(origin) || address >= end || starts[address] {
+			continue
+		}
+
+		i := cpu.DecodeInstruction(ram, uint16(address))
+		if i.Op == cpu.OpInvalid {
+			continue
+		}
+
+		starts[address] = true
+		next := address + int(i.Size())
+
+		switch i.Op {
+		case cpu.OpJump:
+			pending = append(pending, int(i.NNN))
+
+		case cpu.OpCall:
+			pending = append(pending, int(i.NNN), next)
+
+		case cpu.OpReturn, cpu.OpExit, cpu.OpJumpV0:
+
+		case cpu.OpSkipEqualByte, cpu.OpSkipNotEqualByte, cpu.OpSkipEqual,
+			cpu.OpSkipNotEqual, cpu.OpSkipKey, cpu.OpSkipNotKey:
+			skipped := cpu.DecodeInstruction(ram, uint16(next))
+			pending = append(pending, next, next+int(skipped.Size()))
+
+		default:
+			pending = append(pending, next)
+		}
+	}
+
+	lines := []Line{}
+
+	for address := int(origin); address < end; {
+		if starts[address] {
+			i := cpu.DecodeInstruction(ram, uint16(address))
+			size := min(int(i.Size()), end-address)
+
+			lines = append(lines, Line{
+				Address:     uint16(address),
+				Bytes:       ram[address : address+size],
+				Instruction: i,
+			})
+			address += size
+			continue
+		}
+
+		data := address
+		for address < end && !starts[address] && address-data < DATA_PER_LINE {
+			address++
+		}
+
+		lines = append(lines, Line{
+			Address: uint16(data),
+			Bytes:   ram[data:address],
+			Data:    true,
+		})
+	}
+
+	return lines
+}
+
+// Format writes the line as assembly followed by a comment with its address
+// and raw bytes, so a listing can be fed back to an assembler.
+func Format(line Line, syntax Syntax) string {
+	var text string
+	comment := ";"
+
+	switch syntax {
+	case Octo:
+		comment = "#"
+
+		if line.Data {
+			text = joinBytes(line.Bytes, " ")
+		} else {
+			text = line.Instruction.Octo()
+		}
+
+	default:
+		if line.Data {
+			text = "DB " + joinBytes(line.Bytes, ", ")
+		} else {
+			text = line.Instruction.Cowgod()
+		}
+	}
+
+	raw := make([]string, len(line.Bytes))
+	for i, b := range line.Bytes {
+		raw[i] = fmt.Sprintf("%02X", b)
+	}
+
+	note := ""
+	if line.Data {
+		note = "  data"
+	}
+
+	return fmt.Sprintf("%-24s %s 0x%03X  %s%s", text, comment, line.Address, strings.Join(raw, " "), note)
+}
+
+func Write(w io.Writer, lines []Line, syntax Syntax) error {
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, Format(line, syntax)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func joinBytes(data []byte, separator string) string {
+	parts := make([]string, len(data))
+	for i, b := range data {
+		parts[i] = fmt.Sprintf("0x%02X", b)
+	}
+
+	return strings.Join(parts, separator)
+}
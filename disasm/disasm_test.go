@@ -0,0 +1,66 @@
+package disasm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 0x200: SE V0, 0x01, 0x202: CALL 0x208, 0x204: JP 0x204,
+// 0x206: sprite data, 0x208: DRW V0, V1, 5, 0x20A: RET
+var rom = []byte{
+	0x30, 0x01, 0x22, 0x08, 0x12, 0x04, 0xF0, 0x90,
+	0xD0, 0x15, 0x00, 0xEE,
+}
+
+func TestDisassemble(t *testing.T) {
+	lines := Disassemble(rom, 0x200)
+
+	var out bytes.Buffer
+	assert.NoError(t, Write(&out, lines, Cowgod))
+
+	assert.Equal(t, ""+
+		"SE V0, 0x01              ; 0x200  30 01\n"+
+		"CALL 0x208               ; 0x202  22 08\n"+
+		"JP 0x204                 ; 0x204  12 04\n"+
+		"DB 0xF0, 0x90            ; 0x206  F0 90  data\n"+
+		"DRW V0, V1, 5            ; 0x208  D0 15\n"+
+		"RET                      ; 0x20A  00 EE\n",
+		out.String())
+}
+
+func TestDisassembleOcto(t *testing.T) {
+	lines := Disassemble(rom, 0x200)
+
+	var out bytes.Buffer
+	assert.NoError(t, Write(&out, lines, Octo))
+
+	assert.Equal(t, ""+
+		"if v0 != 0x01 then       # 0x200  30 01\n"+
+		":call 0x208              # 0x202  22 08\n"+
+		"jump 0x204               # 0x204  12 04\n"+
+		"0xF0 0x90                # 0x206  F0 90  data\n"+
+		"sprite v0 v1 5           # 0x208  D0 15\n"+
+		"return                   # 0x20A  00 EE\n",
+		out.String())
+}
+
+func TestDisassembleLongLoad(t *testing.T) {
+	// 0x200: SNE V0, 0x00, 0x202: LD I, 0x1234, 0x206: EXIT
+	lines := Disassemble([]byte{0x40, 0x00, 0xF0, 0x00, 0x12, 0x34, 0x00, 0xFD}, 0x200)
+
+	assert.Equal(t, 3, len(lines))
+	assert.Equal(t, "LD I, LONG 0x1234        ; 0x202  F0 00 12 34", Format(lines[1], Cowgod))
+	assert.Equal(t, "i := long 0x1234         # 0x202  F0 00 12 34", Format(lines[1], Octo))
+	assert.Equal(t, uint16(0x206), lines[2].Address)
+}
+
+func TestSyntaxByName(t *testing.T) {
+	syntax, err := SyntaxByName("octo")
+	assert.NoError(t, err)
+	assert.Equal(t, Octo, syntax)
+
+	_, err = SyntaxByName("intel")
+	assert.Error(t, err)
+}
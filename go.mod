@@ -2,12 +2,14 @@ module chip-8
 
 go 1.21.6
 
-require github.com/veandco/go-sdl2 v0.4.38
+require (
+	github.com/stretchr/testify v1.8.4
+	github.com/veandco/go-sdl2 v0.4.38
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.5.0 // indirect
-	github.com/stretchr/testify v1.8.4 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"chip-8/cpu"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// bindings collects every -bind flag.
+type bindings []string
+
+func (b *bindings) String() string {
+	return strings.Join(*b, " ")
+}
+
+func (b *bindings) Set(value string) error {
+	*b = append(*b, value)
+	return nil
+}
+
+// loadKeymap builds the keymap from -keymap, which is a preset name or a
+// keymap file, with the ROM's key hints, and then applies each -bind on
+// top.
+func loadKeymap(name string, hints map[string]uint8, binds bindings, rom_path string, rom_hash [20]byte) (cpu.Keymap, error) {
+	keymap, err := cpu.KeymapByName(name)
+
+	if err == nil {
+		keymap.BindHints(hints)
+	} else {
+		file, open_err := os.Open(name)
+		if open_err != nil {
+			// Not a file either, so the preset error is the useful one
+			return cpu.Keymap{}, err
+		}
+		defer file.Close()
+
+		config, err := cpu.LoadKeymapConfig(file)
+		if err != nil {
+			return cpu.Keymap{}, err
+		}
+
+		if keymap, err = config.Keymap(filepath.Base(rom_path), rom_hash, hints); err != nil {
+			return cpu.Keymap{}, err
+		}
+	}
+
+	for _, binding := range binds {
+		if err := keymap.Bind(binding); err != nil {
+			return cpu.Keymap{}, err
+		}
+	}
+
+	return keymap, nil
+}
@@ -1,15 +1,472 @@
 package main
 
 import (
+	"bufio"
 	"chip-8/cpu"
+	"chip-8/romdb"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+)
+
+// Exit codes for how the ROM stopped, so scripts can tell them apart. 1 is
+// for anything that went wrong outside the ROM and 2 for bad flags.
+const (
+	EXIT_FINISHED  = 0
+	EXIT_CRASHED   = 3
+	EXIT_TIMED_OUT = 4
 )
 
 func main() {
-	rom_path := os.Args[1]
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "disasm":
+			disasmCommand(os.Args[2:])
+			return
+		case "asm":
+			asmCommand(os.Args[2:])
+			return
+		}
+	}
+
+	os.Exit(run())
+}
+
+// run runs the emulator and returns the exit code, so that main only exits
+// once every deferred file has been closed.
+func run() (code int) {
+	headless := flag.Bool("headless", false, "run without opening a window")
+	tui := flag.Bool("tui", false, "draw in the terminal instead of a window, Esc quits")
+	keyRelease := flag.Duration("key-release", cpu.DEFAULT_KEY_RELEASE, "with -tui, how long a key stays down after the terminal last sent it")
+	frames := flag.Int("frames", 0, "number of frames to run in headless mode (0 runs until the ROM halts), exiting with code 4 if it hasn't")
+	output := flag.String("output", "", "file to write the final screen to in headless mode (default stdout)")
+	quirksName := flag.String("quirks", "default", "quirks profile: "+strings.Join(cpu.QuirkProfileNames(), ", "))
+	machineName := flag.String("machine", "chip8", "machine to emulate: "+strings.Join(cpu.MachineNames(), ", "))
+	volume := flag.Float64("volume", cpu.DEFAULT_VOLUME, "buzzer volume from 0 to 1")
+	frequency := flag.Float64("frequency", cpu.DEFAULT_FREQUENCY, "buzzer frequency in Hz")
+	mute := flag.Bool("mute", false, "start with the buzzer muted (toggle with Ctrl+M)")
+	debug := flag.Bool("debug", false, "start in the interactive debugger")
+	ips := flag.Int("ips", cpu.DEFAULT_IPS, "instructions per second (change with Ctrl+- and Ctrl+=)")
+	memoryName := flag.String("memory", "wrap", "what to do when the ROM goes past the end of memory: wrap, halt, or trap to stop in the debugger")
+	timingName := flag.String("timing", "fixed", "instruction timing: fixed runs -ips, vip uses COSMAC VIP instruction times")
+	keymapName := flag.String("keymap", "qwerty", "key layout ("+strings.Join(cpu.KeymapNames(), ", ")+") or a keymap JSON file")
+	var binds bindings
+	flag.Var(&binds, "bind", "bind host keys to a CHIP-8 key, such as 5=Up,W or 6=pad:a (can be repeated)")
+	seed := flag.Uint64("seed", 0, "seed for the random numbers Cxnn returns, so runs can be repeated (default seeds from the clock)")
+	recordPath := flag.String("record", "", "record the keys pressed to this movie file")
+	replayPath := flag.String("replay", "", "replay the keys, seed and quirks from this movie file")
+	tracePath := flag.String("trace", "", "write a trace of every instruction to this file (- for stderr)")
+	traceFormat := flag.String("trace-format", "text", "trace format: text or jsonl")
+	traceRange := flag.String("trace-range", "", "only trace instructions in this hex address range, such as 200-2FF or 200-")
+	traceClass := flag.String("trace-class", "", "only trace opcodes starting with these hex digits, such as 8,D")
+	themeName := flag.String("theme", "", "color theme ("+strings.Join(cpu.ThemeNames(), ", ")+") or a JSON file of colors, default green or the ROM's own colors (Ctrl+T cycles themes)")
+	colorsText := flag.String("colors", "", "background and pixel colors such as #000000,#FFB000, replacing those of the theme (XO-CHIP uses four)")
+	windowScale := flag.Int("window-scale", int(cpu.SCREEN_SCALE), "window pixels to a CHIP-8 pixel when the window opens")
+	scalingName := flag.String("scaling", "aspect", "how the screen fills a resized window: aspect, integer or stretch")
+	screenshotPath := flag.String("screenshot", "", "write the last frame to this PNG file (F12 saves one at any time)")
+	screenshotScale := flag.Int("screenshot-scale", cpu.DEFAULT_CAPTURE_SCALE, "image pixels to a CHIP-8 pixel in screenshots and captures")
+	capturePath := flag.String("capture", "", "capture every frame to this file, as a GIF or raw RGB frames")
+	captureFormat := flag.String("capture-format", "gif", "capture format: gif, or raw for ffmpeg -f rawvideo -pix_fmt rgb24")
+	captureFrames := flag.String("capture-frames", "", "only capture this range of frames, such as 120-300 or 120-")
+	romdbPath := flag.String("romdb", "", "community chip-8-database programs.json to use instead of the built-in ROM database")
+	romdbOverride := flag.String("romdb-override", defaultRomOverridePath(), "ROM database file whose entries replace those in -romdb")
+	rplFlagsDir := flag.String("rpl-flags", defaultRPLFlagsDir(), "directory to keep each ROM's SUPER-CHIP RPL flags in between runs (empty to not keep them)")
+	flag.Parse()
+
+	// Settings from the ROM database only apply where no flag was given
+	explicit := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	machine, err := cpu.MachineByName(*machineName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	quirks, err := cpu.QuirksByName(*quirksName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if *ips < cpu.MIN_IPS || *ips > cpu.MAX_IPS {
+		fmt.Fprintf(os.Stderr, "-ips must be from %d to %d\n", cpu.MIN_IPS, cpu.MAX_IPS)
+		return 2
+	}
+
+	if *volume < 0 || *volume > 1 {
+		fmt.Fprintln(os.Stderr, "-volume must be from 0 to 1")
+		return 2
+	}
+
+	if *frequency <= 0 {
+		fmt.Fprintln(os.Stderr, "-frequency must be above 0")
+		return 2
+	}
+
+	rom_path := flag.Arg(0)
+
+	db, err := loadRomDatabase(*romdbPath, *romdbOverride)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	// A ROM that can't be read is reported by LoadRom below
+	var rom romdb.Rom
+	if rom_hash, err := hashRom(rom_path); err == nil {
+		rom, _ = db.Lookup(rom_hash)
+	}
+
+	if rom.Platform != "" {
+		if !explicit["machine"] {
+			machine = rom.Machine
+		}
+
+		if !explicit["quirks"] {
+			quirks = rom.Quirks
+		}
+
+		if !explicit["ips"] && rom.TickRate > 0 {
+			*ips = min(max(rom.TickRate*60, cpu.MIN_IPS), cpu.MAX_IPS)
+		}
+	}
+
+	colors := rom.Colors
+	if *themeName != "" {
+		if colors, err = loadTheme(*themeName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	if *colorsText != "" {
+		custom, err := cpu.ParseColors(*colorsText)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		colors = overrideColors(colors, custom)
+	}
+
+	scaling, err := cpu.ScalingByName(*scalingName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	memory_policy, err := cpu.MemoryPolicyByName(*memoryName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
 	emu := cpu.NewEmulator()
-	emu.LoadRom(rom_path)
+	emu.Quirks = quirks
+	emu.Machine = machine
+	emu.MemoryPolicy = memory_policy
+	if explicit["seed"] {
+		emu.Random = cpu.NewRand(*seed)
+	}
+
+	var replay *cpu.Movie
+	if *replayPath != "" {
+		if replay, err = loadMovieFile(*replayPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		// The machine decides how big a ROM can be, so set it before loading
+		emu.Machine, _ = cpu.MachineByName(replay.Machine)
+	}
+
+	if err := emu.LoadRom(rom_path); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// Playing a movie to its end is a finished run, not a timed out one
+	played_to_end := false
+	if replay != nil {
+		if err := replay.Apply(&emu); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		if *frames == 0 {
+			*frames = replay.Frames
+			played_to_end = true
+		}
+
+		if replay.Timing != "" {
+			*timingName = replay.Timing
+		}
+
+		if replay.IPS != 0 {
+			*ips = replay.IPS
+		}
+	}
+
+	timing, err := cpu.TimingByName(*timingName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	// Movies start with the flags cleared, so they play back the same
+	saveFlags := func() {}
+	if *rplFlagsDir != "" && *recordPath == "" && replay == nil {
+		if err := loadRPLFlags(*rplFlagsDir, &emu); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		loaded_flags := emu.RPLFlags
+		saveFlags = func() {
+			if emu.RPLFlags == loaded_flags {
+				return
+			}
+			if err := saveRPLFlags(*rplFlagsDir, &emu); err != nil {
+				fmt.Fprintln(os.Stderr, "rpl flags:", err)
+			}
+		}
+	}
+
+	var record *cpu.Movie
+	if *recordPath != "" {
+		movie := cpu.NewMovie(&emu)
+		movie.Timing = *timingName
+		movie.IPS = *ips
+		record = &movie
+	}
+
+	saveRecord := func() {
+		if record == nil {
+			return
+		}
+		if err := saveMovieFile(*recordPath, record); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	closeTrace := func() {}
+	if *tracePath != "" {
+		tracer, err := newTraceWriter(*traceFormat, *traceRange, *traceClass)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+
+		trace_file := os.Stderr
+		if *tracePath != "-" {
+			if trace_file, err = os.Create(*tracePath); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+		}
+
+		trace_output := bufio.NewWriter(trace_file)
+		tracer.Output = trace_output
+		emu.Tracer = tracer
+
+		closeTrace = func() {
+			err := trace_output.Flush()
+			if trace_file != os.Stderr {
+				err = errors.Join(err, trace_file.Close())
+			}
+			if err != nil || tracer.Err() != nil {
+				fmt.Fprintln(os.Stderr, "trace:", errors.Join(tracer.Err(), err))
+			}
+		}
+	}
+
+	var capture *cpu.Capture
+	closeCapture := func() {}
+	if *capturePath != "" {
+		if capture, err = newCapture(*captureFormat, *captureFrames, *screenshotScale); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		capture.Colors = colors
+
+		capture_file, err := os.Create(*capturePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		capture_output := bufio.NewWriter(capture_file)
+		capture.Output = capture_output
+
+		closeCapture = func() {
+			err := capture.Close()
+			if err == nil {
+				err = capture_output.Flush()
+			}
+			err = errors.Join(err, capture_file.Close())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "capture:", err)
+			}
+		}
+	}
+
+	var screenshot *os.File
+	if *screenshotPath != "" {
+		if screenshot, err = os.Create(*screenshotPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer closeOutput(screenshot, &code)
+	}
+
+	if *debug {
+		runDebugger(&emu)
+		saveFlags()
+		saveRecord()
+		closeTrace()
+		closeCapture()
+
+		if screenshot != nil {
+			if err := cpu.WriteScreenshot(screenshot, emu.Screen, emu.ScreenWidth, emu.ScreenHeight, *screenshotScale, colors); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+
+		if emu.HaltReason() == cpu.HaltError {
+			return EXIT_CRASHED
+		}
+		return EXIT_FINISHED
+	}
+
+	if *headless {
+		runner := cpu.Headless{Frames: *frames, Output: os.Stdout, Record: record, Replay: replay}
+		runner.Timing = timing
+		runner.InstructionsPerSecond = *ips
+		runner.Capture = capture
+		runner.Debug = runDebugger
+		runner.ScreenshotScale = *screenshotScale
+		runner.Colors = colors
+		if screenshot != nil {
+			runner.Screenshot = screenshot
+		}
+
+		if *output != "" {
+			file, err := os.Create(*output)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			defer closeOutput(file, &code)
+
+			runner.Output = file
+		}
+
+		err = runner.Run(&emu)
+	} else if *tui {
+		terminal := cpu.Terminal{KeyRelease: *keyRelease, Record: record, Replay: replay}
+		terminal.Timing = timing
+		terminal.InstructionsPerSecond = *ips
+		terminal.Colors = colors
+		terminal.Capture = capture
+		terminal.Debug = runDebuggerOn
+		terminal.ScreenshotScale = *screenshotScale
+		if screenshot != nil {
+			terminal.Screenshot = screenshot
+		}
+		if terminal.Keymap, err = loadKeymap(*keymapName, rom.Keys, binds, rom_path, emu.RomHash); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		err = terminal.Run(&emu)
+	} else {
+		display := cpu.Display{Beeper: cpu.NewBeeper()}
+		display.Beeper.Volume = *volume
+		display.Beeper.Frequency = *frequency
+		display.Beeper.Muted = *mute
+		display.StatePath = rom_path
+		display.Timing = timing
+		display.InstructionsPerSecond = *ips
+		display.Title = rom.Title
+		display.Colors = colors
+		display.Scale = *windowScale
+		display.Scaling = scaling
+		if display.Keymap, err = loadKeymap(*keymapName, rom.Keys, binds, rom_path, emu.RomHash); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		display.Record = record
+		display.Replay = replay
+		display.Capture = capture
+		display.Debug = runDebugger
+		display.ScreenshotPath = strings.TrimSuffix(rom_path, filepath.Ext(rom_path))
+		display.ScreenshotScale = *screenshotScale
+		if screenshot != nil {
+			display.Screenshot = screenshot
+		}
+		err = display.Run(&emu)
+	}
+
+	saveFlags()
+	saveRecord()
+	closeTrace()
+	closeCapture()
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		if emu.HaltReason() == cpu.HaltError {
+			return EXIT_CRASHED
+		}
+		return 1
+	}
+
+	// Only a headless run stops without the ROM halting or the user quitting
+	if *headless && !emu.Halted() && !played_to_end {
+		fmt.Fprintf(os.Stderr, "The ROM was still running after %d frames\n", *frames)
+		return EXIT_TIMED_OUT
+	}
+
+	return EXIT_FINISHED
+}
+
+// closeOutput closes a file the run wrote to. Losing what was written
+// turns a finished run into a failed one.
+func closeOutput(file *os.File, code *int) {
+	if err := file.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if *code == EXIT_FINISHED {
+			*code = 1
+		}
+	}
+}
+
+// runDebugger runs the debugger on the terminal until the user quits.
+// Ctrl+C interrupts a running continue.
+func runDebugger(emu *cpu.Emulator) {
+	runDebuggerOn(emu, os.Stdin)
+}
+
+// runDebuggerOn runs the debugger with commands read from input, for -tui,
+// which is already reading stdin.
+func runDebuggerOn(emu *cpu.Emulator, input io.Reader) {
+	debugger := cpu.NewDebugger(emu, input, os.Stdout)
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	go func() {
+		for range interrupts {
+			debugger.Interrupt()
+		}
+	}()
 
-	display := cpu.Display{}
-	display.Run(emu)
+	debugger.Run()
 }
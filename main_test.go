@@ -0,0 +1,120 @@
+package main
+
+import (
+	"chip-8/cpu"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runWith runs the emulator as if started with args, without touching the
+// user's ROM database or flags.
+func runWith(t *testing.T, args ...string) int {
+	t.Helper()
+
+	flag.CommandLine = flag.NewFlagSet("chip-8", flag.ContinueOnError)
+	os.Args = append([]string{"chip-8", "-rpl-flags", "", "-romdb-override", ""}, args...)
+	return run()
+}
+
+// writeLoop writes a ROM that never halts.
+func writeLoop(t *testing.T, dir string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "loop.ch8")
+	assert.NoError(t, os.WriteFile(path, []byte{0x70, 0x01, 0x12, 0x00}, 0o644))
+	return path
+}
+
+func TestRun(t *testing.T) {
+	t.Run("Times out a headless ROM that is still running", func(t *testing.T) {
+		dir := t.TempDir()
+		rom := writeLoop(t, dir)
+
+		code := runWith(t, "-headless", "-frames", "10", "-output", filepath.Join(dir, "screen.txt"), rom)
+
+		assert.Equal(t, EXIT_TIMED_OUT, code)
+	})
+
+	t.Run("Finishes a replay played to its end", func(t *testing.T) {
+		dir := t.TempDir()
+		rom := writeLoop(t, dir)
+		movie := filepath.Join(dir, "loop.movie")
+		output := filepath.Join(dir, "screen.txt")
+
+		runWith(t, "-headless", "-frames", "10", "-record", movie, "-output", output, rom)
+		code := runWith(t, "-headless", "-replay", movie, "-output", output, rom)
+
+		assert.Equal(t, EXIT_FINISHED, code)
+	})
+
+	t.Run("Saves the movie recorded in the debugger", func(t *testing.T) {
+		dir := t.TempDir()
+		rom := writeLoop(t, dir)
+		movie := filepath.Join(dir, "loop.movie")
+
+		commands := filepath.Join(dir, "commands.txt")
+		assert.NoError(t, os.WriteFile(commands, []byte("step\nquit\n"), 0o644))
+		stdin, err := os.Open(commands)
+		assert.NoError(t, err)
+		defer stdin.Close()
+
+		saved := os.Stdin
+		os.Stdin = stdin
+		defer func() { os.Stdin = saved }()
+
+		code := runWith(t, "-debug", "-record", movie, rom)
+
+		assert.Equal(t, EXIT_FINISHED, code)
+		assert.FileExists(t, movie)
+	})
+}
+
+func TestRunFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"-ips", "0"},
+		{"-ips", "-600"},
+		{"-frequency", "0"},
+		{"-frequency", "-440"},
+	} {
+		t.Run("Rejects "+strings.Join(args, " "), func(t *testing.T) {
+			rom := writeLoop(t, t.TempDir())
+
+			assert.Equal(t, 2, runWith(t, append(append(args, "-headless", "-frames", "1"), rom)...))
+		})
+	}
+}
+
+func TestParseAddressRange(t *testing.T) {
+	tests := []struct {
+		text string
+		from uint16
+		to   uint16
+	}{
+		{"200-2FF", 0x200, 0x2FF},
+		{"0x200-0x2ff", 0x200, 0x2FF},
+		{"300", 0x300, 0x300},
+		{"0-0", 0, 0},
+		{"200-", 0x200, cpu.TRACE_END},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			from, to, err := parseAddressRange(test.text)
+			assert.NoError(t, err)
+			assert.Equal(t, test.from, from)
+			assert.Equal(t, test.to, to)
+		})
+	}
+
+	for _, text := range []string{"0x200-0", "2FF-200", "-200", "200-XYZ"} {
+		t.Run("Rejects "+text, func(t *testing.T) {
+			_, _, err := parseAddressRange(text)
+			assert.Error(t, err)
+		})
+	}
+}
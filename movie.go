@@ -0,0 +1,35 @@
+package main
+
+import (
+	"chip-8/cpu"
+	"os"
+)
+
+func loadMovieFile(path string) (*cpu.Movie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	movie, err := cpu.LoadMovie(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &movie, nil
+}
+
+func saveMovieFile(path string, movie *cpu.Movie) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := movie.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"chip-8/cpu"
+	"image/color"
+	"os"
+)
+
+// loadTheme returns the colors for -theme, which is a theme name or a theme
+// file.
+func loadTheme(name string) ([]color.RGBA, error) {
+	colors, err := cpu.ThemeByName(name)
+	if err == nil {
+		return colors, nil
+	}
+
+	file, open_err := os.Open(name)
+	if open_err != nil {
+		// Not a file either, so the theme error is the useful one
+		return nil, err
+	}
+	defer file.Close()
+
+	return cpu.LoadColors(file)
+}
+
+// overrideColors replaces the first colors of base with those in custom,
+// so -colors can change just the background of a theme.
+func overrideColors(base []color.RGBA, custom []color.RGBA) []color.RGBA {
+	colors := append([]color.RGBA{}, base...)
+
+	for i, c := range custom {
+		if i < len(colors) {
+			colors[i] = c
+		} else {
+			colors = append(colors, c)
+		}
+	}
+
+	return colors
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"chip-8/romdb"
+	"crypto/sha1"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// loadRomDatabase starts from the built-in database, or from the file at
+// path when it's set, and then replaces entries with any in override_path.
+// A missing override file is not an error.
+func loadRomDatabase(path string, override_path string) (romdb.Database, error) {
+	db := romdb.Builtin()
+
+	if path != "" {
+		file_db, err := loadRomDatabaseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		db = file_db
+	}
+
+	if override_path != "" {
+		override, err := loadRomDatabaseFile(override_path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		db.Merge(override)
+	}
+
+	return db, nil
+}
+
+func loadRomDatabaseFile(path string) (romdb.Database, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return romdb.Load(file)
+}
+
+// defaultRomOverridePath is where the override file lives when
+// -romdb-override isn't given.
+func defaultRomOverridePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "chip-8", "roms.json")
+}
+
+// hashRom returns the SHA-1 LoadRom will give the ROM, so its settings can
+// be looked up before it's loaded.
+func hashRom(path string) ([20]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [20]byte{}, err
+	}
+
+	return sha1.Sum(data), nil
+}
@@ -0,0 +1,44 @@
+package romdb
+
+import "chip-8/cpu"
+
+type platform struct {
+	machine  cpu.Machine
+	quirks   cpu.Quirks
+	tickRate int
+}
+
+// The platforms from the community database that this emulator can run,
+// with its closest quirks profile and the database's default tick rate
+var platforms = map[string]platform{
+	"originalChip8": {cpu.MachineChip8, cpu.QuirksCosmacVIP, 15},
+	"hybridVIP":     {cpu.MachineChip8, cpu.QuirksCosmacVIP, 15},
+	"modernChip8":   {cpu.MachineChip8, cpu.Quirks{}, 12},
+	"chip48":        {cpu.MachineChip8, cpu.QuirksChip48, 30},
+	"superchip1":    {cpu.MachineSuperChip, cpu.QuirksSuperChipLegacy, 30},
+	"superchip":     {cpu.MachineSuperChip, cpu.QuirksSuperChipModern, 30},
+	"xochip":        {cpu.MachineXOChip, cpu.QuirksXOChip, 100},
+}
+
+// applyQuirks sets the quirks a ROM lists for its platform. The database
+// names them after what the ROM expects, so some are the opposite of ours.
+func applyQuirks(quirks *cpu.Quirks, flags map[string]bool) {
+	for name, on := range flags {
+		switch name {
+		case "shift":
+			quirks.ShiftVy = !on
+		case "memoryIncrementByX":
+			quirks.MemoryIncrementByX = on
+		case "memoryLeaveIUnchanged":
+			quirks.MemoryIncrement = !on
+		case "wrap":
+			quirks.Clipping = !on
+		case "jump":
+			quirks.JumpVx = on
+		case "logic":
+			quirks.VFReset = on
+		case "vblank":
+			quirks.DisplayWait = on
+		}
+	}
+}
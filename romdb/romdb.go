@@ -0,0 +1,141 @@
+// Package romdb looks up settings for known ROMs by the SHA-1 of their
+// data. It reads the programs.json format of the community CHIP-8
+// database (https://github.com/chip-8/chip-8-database) and comes with a
+// small built-in database for the ROMs in this repository. Point Load at
+// the full community file to know about more games.
+package romdb
+
+import (
+	"chip-8/cpu"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+//go:embed programs.json
+var builtin string
+
+// Rom is what the database knows about one ROM.
+type Rom struct {
+	Title    string
+	Platform string
+	Machine  cpu.Machine
+	Quirks   cpu.Quirks
+
+	// TickRate is instructions per frame, 0 if unknown
+	TickRate int
+
+	// Colors are the pixel colors, background first. Empty if unknown.
+	Colors []color.RGBA
+
+	// Keys are hints such as "up" or "a" for the CHIP-8 keys the ROM uses
+	Keys map[string]uint8
+}
+
+// Database maps the hex SHA-1 of a ROM to its settings.
+type Database map[string]Rom
+
+// Builtin returns the database built into the emulator.
+func Builtin() Database {
+	db, err := Load(strings.NewReader(builtin))
+	if err != nil {
+		panic(err)
+	}
+
+	return db
+}
+
+// Lookup finds the ROM with the given SHA-1.
+func (d Database) Lookup(hash [20]byte) (Rom, bool) {
+	rom, ok := d[hex.EncodeToString(hash[:])]
+	return rom, ok
+}
+
+// Merge adds every ROM in other, replacing any with the same SHA-1.
+func (d Database) Merge(other Database) {
+	for hash, rom := range other {
+		d[hash] = rom
+	}
+}
+
+type program struct {
+	Title string                `json:"title"`
+	Roms  map[string]programRom `json:"roms"`
+}
+
+type programRom struct {
+	Platforms       []string                   `json:"platforms"`
+	TickRate        int                        `json:"tickrate"`
+	QuirkyPlatforms map[string]map[string]bool `json:"quirkyPlatforms"`
+	Keys            map[string]uint8           `json:"keys"`
+	Colors          struct {
+		Pixels []string `json:"pixels"`
+	} `json:"colors"`
+}
+
+// Load reads a database in the programs.json format. ROMs that only run
+// on platforms this emulator doesn't have are left out.
+func Load(r io.Reader) (Database, error) {
+	var programs []program
+	if err := json.NewDecoder(r).Decode(&programs); err != nil {
+		return nil, fmt.Errorf("not a ROM database: %w", err)
+	}
+
+	db := Database{}
+
+	for _, p := range programs {
+		for hash, entry := range p.Roms {
+			rom, ok, err := newRom(p.Title, entry)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", p.Title, err)
+			}
+
+			if ok {
+				db[strings.ToLower(hash)] = rom
+			}
+		}
+	}
+
+	return db, nil
+}
+
+func newRom(title string, entry programRom) (Rom, bool, error) {
+	rom := Rom{Title: title, Keys: entry.Keys}
+
+	found := false
+	for _, id := range entry.Platforms {
+		if p, ok := platforms[id]; ok {
+			rom.Platform = id
+			rom.Machine = p.machine
+			rom.Quirks = p.quirks
+			rom.TickRate = p.tickRate
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return Rom{}, false, nil
+	}
+
+	if entry.TickRate > 0 {
+		rom.TickRate = entry.TickRate
+	}
+
+	applyQuirks(&rom.Quirks, entry.QuirkyPlatforms[rom.Platform])
+
+	for _, text := range entry.Colors.Pixels {
+		c, err := cpu.ParseColor(text)
+		if err != nil {
+			return Rom{}, false, err
+		}
+
+		rom.Colors = append(rom.Colors, c)
+	}
+
+	return rom, true, nil
+}
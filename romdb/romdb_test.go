@@ -0,0 +1,92 @@
+package romdb
+
+import (
+	"chip-8/cpu"
+	"crypto/sha1"
+	"image/color"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltin(t *testing.T) {
+	db := Builtin()
+
+	for _, path := range []string{"ibm-logo.ch8", "pong.rom", "test-opcode.ch8"} {
+		t.Run("Knows "+path, func(t *testing.T) {
+			data, err := os.ReadFile("../roms/" + path)
+			assert.NoError(t, err)
+
+			_, ok := db.Lookup(sha1.Sum(data))
+			assert.True(t, ok)
+		})
+	}
+
+	t.Run("Reads key hints", func(t *testing.T) {
+		rom := db["b232ef880bd6060fb45fa6effed7edf0ae95670e"]
+		assert.Equal(t, "Pong", rom.Title)
+		assert.Equal(t, cpu.QuirksCosmacVIP, rom.Quirks)
+		assert.Equal(t, 15, rom.TickRate)
+		assert.Equal(t, uint8(1), rom.Keys["up"])
+	})
+
+	t.Run("Misses unknown ROMs", func(t *testing.T) {
+		_, ok := db.Lookup([20]byte{1})
+		assert.False(t, ok)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	db, err := Load(strings.NewReader(`[
+		{
+			"title": "Game",
+			"roms": {
+				"AA00000000000000000000000000000000000000": {
+					"platforms": ["megachip8", "superchip"],
+					"tickrate": 50,
+					"quirkyPlatforms": {"superchip": {"shift": false, "jump": false}},
+					"colors": {"pixels": ["#000000", "#ff8000"]}
+				},
+				"bb00000000000000000000000000000000000000": {
+					"platforms": ["megachip8"]
+				}
+			}
+		}
+	]`))
+	assert.NoError(t, err)
+
+	rom, ok := db["aa00000000000000000000000000000000000000"]
+	assert.True(t, ok)
+	assert.Equal(t, "superchip", rom.Platform)
+	assert.Equal(t, cpu.MachineSuperChip, rom.Machine)
+	assert.Equal(t, 50, rom.TickRate)
+	assert.True(t, rom.Quirks.ShiftVy)
+	assert.False(t, rom.Quirks.JumpVx)
+	assert.Equal(t, []color.RGBA{{0, 0, 0, 255}, {255, 128, 0, 255}}, rom.Colors)
+
+	t.Run("Leaves out ROMs for other platforms", func(t *testing.T) {
+		assert.NotContains(t, db, "bb00000000000000000000000000000000000000")
+	})
+
+	t.Run("Rejects bad colors", func(t *testing.T) {
+		_, err := Load(strings.NewReader(`[{"title": "Game", "roms": {"aa": {
+			"platforms": ["xochip"], "colors": {"pixels": ["red"]}
+		}}}]`))
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects files that aren't databases", func(t *testing.T) {
+		_, err := Load(strings.NewReader(`{}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestMerge(t *testing.T) {
+	db := Builtin()
+	db.Merge(Database{"b232ef880bd6060fb45fa6effed7edf0ae95670e": {Title: "My Pong", TickRate: 20}})
+
+	assert.Equal(t, "My Pong", db["b232ef880bd6060fb45fa6effed7edf0ae95670e"].Title)
+	assert.Equal(t, "IBM Logo", db["1ba58656810b67fd131eb9af3e3987863bf26c90"].Title)
+}
@@ -0,0 +1,178 @@
+; Checks VF after the 8xy_ arithmetic. Each test draws a mark for VF and
+; then one for the result, every mark should be a tick:
+;
+;   row 1: 8xy4 without and with a carry, 8xy5 without, with and with an
+;          equal borrow
+;   row 2: 8xy7 without and with a borrow, 8xy6 shifting out a 1 and a 0,
+;          8xyE shifting out a 1
+;   row 3: 8xyE shifting out a 0, VF as Vy, then VF as Vx for 8xy4, 8xy5
+;          and 8xy6 (VF only) and 7xnn leaving VF alone
+;
+; Shifts use Vx as Vy too, so they pass with or without the ShiftVy quirk.
+
+        LD V8, 0
+        LD V9, 0
+
+        ; 8xy4: 10 + 20
+        LD V2, 10
+        LD V3, 20
+        ADD V2, V3
+        LD V0, VF
+        LD V1, 0
+        CALL check
+        LD V0, V2
+        LD V1, 30
+        CALL check
+
+        ; 8xy4: 200 + 100
+        LD V2, 200
+        LD V3, 100
+        ADD V2, V3
+        LD V0, VF
+        LD V1, 1
+        CALL check
+        LD V0, V2
+        LD V1, 44
+        CALL check
+
+        ; 8xy5: 30 - 10
+        LD V2, 30
+        LD V3, 10
+        SUB V2, V3
+        LD V0, VF
+        LD V1, 1
+        CALL check
+        LD V0, V2
+        LD V1, 20
+        CALL check
+
+        ; 8xy5: 10 - 30
+        LD V2, 10
+        LD V3, 30
+        SUB V2, V3
+        LD V0, VF
+        LD V1, 0
+        CALL check
+        LD V0, V2
+        LD V1, 236
+        CALL check
+
+        ; 8xy5: 10 - 10
+        LD V2, 10
+        LD V3, 10
+        SUB V2, V3
+        LD V0, VF
+        LD V1, 1
+        CALL check
+        LD V0, V2
+        LD V1, 0
+        CALL check
+
+        ; 8xy7: 30 - 10
+        LD V2, 10
+        LD V3, 30
+        SUBN V2, V3
+        LD V0, VF
+        LD V1, 1
+        CALL check
+        LD V0, V2
+        LD V1, 20
+        CALL check
+
+        ; 8xy7: 10 - 30
+        LD V2, 30
+        LD V3, 10
+        SUBN V2, V3
+        LD V0, VF
+        LD V1, 0
+        CALL check
+        LD V0, V2
+        LD V1, 236
+        CALL check
+
+        ; 8xy6: 5 >> 1
+        LD V2, 5
+        SHR V2, V2
+        LD V0, VF
+        LD V1, 1
+        CALL check
+        LD V0, V2
+        LD V1, 2
+        CALL check
+
+        ; 8xy6: 4 >> 1
+        LD V2, 4
+        SHR V2, V2
+        LD V0, VF
+        LD V1, 0
+        CALL check
+        LD V0, V2
+        LD V1, 2
+        CALL check
+
+        ; 8xyE: 0x81 << 1
+        LD V2, 0x81
+        SHL V2, V2
+        LD V0, VF
+        LD V1, 1
+        CALL check
+        LD V0, V2
+        LD V1, 2
+        CALL check
+
+        ; 8xyE: 0x41 << 1
+        LD V2, 0x41
+        SHL V2, V2
+        LD V0, VF
+        LD V1, 0
+        CALL check
+        LD V0, V2
+        LD V1, 0x82
+        CALL check
+
+        ; 8xy4 with VF as Vy: 200 + 100
+        LD V2, 200
+        LD VF, 100
+        ADD V2, VF
+        LD V0, VF
+        LD V1, 1
+        CALL check
+        LD V0, V2
+        LD V1, 44
+        CALL check
+
+        ; The flag is written last, so it's what's left in VF as Vx
+        LD VF, 200
+        LD V3, 100
+        ADD VF, V3
+        LD V0, VF
+        LD V1, 1
+        CALL check
+
+        LD VF, 10
+        LD V3, 30
+        SUB VF, V3
+        LD V0, VF
+        LD V1, 0
+        CALL check
+
+        LD VF, 0x81
+        SHR VF, VF
+        LD V0, VF
+        LD V1, 1
+        CALL check
+
+        ; 7xnn: 255 + 1 doesn't carry
+        LD VF, 5
+        LD V2, 255
+        ADD V2, 1
+        LD V0, VF
+        LD V1, 5
+        CALL check
+        LD V0, V2
+        LD V1, 0
+        CALL check
+
+done:   JP done
+
+        INCLUDE "marks.asm"
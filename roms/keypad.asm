@@ -0,0 +1,37 @@
+; Checks the keypad instructions. Press and release a key, then press
+; and release A:
+;
+;   1. the key Fx0A returned
+;   2. a tick when the key was already up once Fx0A returned
+;   3. A, once Ex9E sees it down
+;   4. a tick once ExA1 sees it up again
+
+        LD V8, 0
+        LD V9, 0
+
+        LD V2, K
+        LD V0, V2
+        CALL digit
+
+        ; Fx0A waits for the key to go up as well as down
+        LD V0, 1
+        SKNP V2
+        LD V0, 0
+        LD V1, 1
+        CALL check
+
+        LD V2, 0xA
+down:   SKP V2
+        JP down
+        LD V0, V2
+        CALL digit
+
+up:     SKNP V2
+        JP up
+        LD V0, 1
+        LD V1, 1
+        CALL check
+
+done:   JP done
+
+        INCLUDE "marks.asm"
@@ -0,0 +1,23 @@
+; Shared by the conformance ROMs. Marks are drawn left to right from V8,
+; V9 in rows of ten. They use V0, V1, V8, V9, VF and I.
+
+; check draws a tick when V0, the value a test got, equals V1, the value
+; it should have got, and a cross when it doesn't.
+check:  LD I, tick
+        SE V0, V1
+        LD I, cross
+        JP mark
+
+; digit draws V0 as a hex digit.
+digit:  LD F, V0
+mark:   DRW V8, V9, 5
+        ADD V8, 6
+        SE V8, 60
+        RET
+newline:
+        LD V8, 0
+        ADD V9, 6
+        RET
+
+tick:   DB %00000000, %00001000, %00010000, %10100000, %01000000
+cross:  DB %10001000, %01010000, %00100000, %01010000, %10001000
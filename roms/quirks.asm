@@ -0,0 +1,97 @@
+; Shows which quirks the interpreter has, as a row of digits:
+;
+;   1. VFReset, 1 when 8xy1 resets VF
+;   2. MemoryIncrement, 2 when Fx65 leaves I past the last register, 1
+;      when it adds x and 0 when it leaves I alone
+;   3. ShiftVy, 1 when 8xy6 shifts Vy
+;   4. Clipping, 1 when sprites are cut off at the edge
+;   5. JumpVx, 1 when Bxnn adds Vx
+;   6. DisplayWait, 1 when drawing waits for the next frame
+
+        ; Bnnn jumps here, to jump_off plus V0 or plus V2. It has to come
+        ; first so that x in Bxnn is 2.
+        JP start
+jumps:  JP jump_off
+        JP jump_on
+
+start:  LD V8, 0
+        LD V9, 0
+
+        ; VFReset
+        LD VF, 5
+        OR V2, V3
+        LD V0, 1
+        SE VF, 0
+        LD V0, 0
+        CALL digit
+
+        ; MemoryIncrement: the byte at I after loading V0 and V1 says how
+        ; far I moved
+        LD I, scratch
+        LD V1, [I]
+        LD V0, [I]
+        LD V1, 0xA0
+        SUB V0, V1
+        CALL digit
+
+        ; ShiftVy
+        LD V2, 1
+        LD V3, 4
+        SHR V2, V3
+        LD V0, 0
+        SNE V2, 2
+        LD V0, 1
+        CALL digit
+
+        ; Clipping: a sprite that wraps off the right edge hits the pixel
+        ; at the left
+        LD I, pixel
+        LD V2, 0
+        LD V3, 30
+        DRW V2, V3, 1
+        LD V4, 60
+        LD I, row
+        DRW V4, V3, 1
+        LD V0, VF
+        DRW V4, V3, 1
+        LD I, pixel
+        DRW V2, V3, 1
+        LD V1, 1
+        XOR V0, V1
+        CALL digit
+
+        ; JumpVx
+        LD V0, 0
+        LD V2, 2
+        JP V0, jumps
+jump_off:
+        LD V0, 0
+        JP jumped
+jump_on:
+        LD V0, 1
+jumped: CALL digit
+
+        ; DisplayWait: count blank sprites drawn in 10 frames, one a frame
+        ; when drawing waits and more than 15 when it doesn't
+        LD V4, 0
+        LD V5, 10
+        LD DT, V5
+        LD I, blank
+wait:   DRW V2, V3, 1
+        ADD V4, 1
+        LD V5, DT
+        SE V5, 0
+        JP wait
+        LD V1, 15
+        SUB V1, V4
+        LD V0, VF
+        CALL digit
+
+done:   JP done
+
+scratch: DB 0xA0, 0xA1, 0xA2, 0xA3
+pixel:  DB %10000000
+row:    DB %11111111
+blank:  DB 0
+
+        INCLUDE "marks.asm"
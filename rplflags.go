@@ -0,0 +1,59 @@
+package main
+
+import (
+	"chip-8/cpu"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// defaultRPLFlagsDir is the directory for -rpl-flags, next to roms.json.
+func defaultRPLFlagsDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "chip-8", "flags")
+}
+
+// rplFlagsPath is the file the ROM's RPL flags are kept in, named after
+// its SHA-1 so a ROM keeps them wherever it's run from.
+func rplFlagsPath(dir string, rom_hash [20]byte) string {
+	return filepath.Join(dir, hex.EncodeToString(rom_hash[:])+".flags")
+}
+
+// loadRPLFlags loads the flags the ROM saved on an earlier run. A ROM that
+// never saved any starts with them all 0.
+func loadRPLFlags(dir string, emu *cpu.Emulator) error {
+	file, err := os.Open(rplFlagsPath(dir, emu.RomHash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return emu.LoadRPLFlags(file)
+}
+
+func saveRPLFlags(dir string, emu *cpu.Emulator) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(rplFlagsPath(dir, emu.RomHash))
+	if err != nil {
+		return err
+	}
+
+	if err := emu.SaveRPLFlags(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"chip-8/cpu"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseAddressRange parses "200-2FF" into an inclusive range of hex
+// addresses. "200-" runs to the end of memory and a single address traces
+// just that one.
+func parseAddressRange(text string) (uint16, uint16, error) {
+	from_text, to_text, found := strings.Cut(text, "-")
+	if !found {
+		to_text = from_text
+	}
+
+	from, err := parseAddress(from_text)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if strings.TrimSpace(to_text) == "" {
+		return from, cpu.TRACE_END, nil
+	}
+
+	to, err := parseAddress(to_text)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if to < from {
+		return 0, 0, fmt.Errorf("address range %q ends before it starts", text)
+	}
+
+	return from, to, nil
+}
+
+func parseAddress(text string) (uint16, error) {
+	text = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(text)), "0x")
+
+	address, err := strconv.ParseUint(text, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", text)
+	}
+
+	return uint16(address), nil
+}
+
+// parseOpcodeClasses parses a list of opcode first nibbles such as "8,D".
+func parseOpcodeClasses(text string) ([]uint8, error) {
+	classes := []uint8{}
+
+	for _, field := range strings.Split(text, ",") {
+		class, err := strconv.ParseUint(strings.TrimSpace(field), 16, 4)
+		if err != nil {
+			return nil, fmt.Errorf("invalid opcode class %q, use a hex digit such as D", field)
+		}
+
+		classes = append(classes, uint8(class))
+	}
+
+	return classes, nil
+}
+
+func newTraceWriter(format_name string, address_range string, classes string) (*cpu.TraceWriter, error) {
+	format, err := cpu.TraceFormatByName(format_name)
+	if err != nil {
+		return nil, err
+	}
+
+	tracer := cpu.TraceWriter{Format: format}
+
+	if address_range != "" {
+		tracer.Ranged = true
+		if tracer.From, tracer.To, err = parseAddressRange(address_range); err != nil {
+			return nil, err
+		}
+	}
+
+	if classes != "" {
+		if tracer.Classes, err = parseOpcodeClasses(classes); err != nil {
+			return nil, err
+		}
+	}
+
+	return &tracer, nil
+}